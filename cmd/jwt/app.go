@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/2SSK/jwt/internal/config"
+	"github.com/2SSK/jwt/internal/model/user"
+	"github.com/2SSK/jwt/internal/repository"
+	"github.com/2SSK/jwt/internal/server"
+	"github.com/2SSK/jwt/internal/service"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"golang.org/x/term"
+)
+
+// app is what the admin commands work with: the same repositories and services
+// the server uses
+type app struct {
+	server   *server.Server
+	repos    *repository.Repositories
+	services *service.Services
+}
+
+func newApp(cfg *config.Config, log *zerolog.Logger) (*app, error) {
+	srv, err := server.New(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+
+	repos := repository.NewRepositories(srv)
+	services, err := service.NewServices(srv, repos)
+	if err != nil {
+		srv.DB.Close()
+		return nil, err
+	}
+
+	return &app{server: srv, repos: repos, services: services}, nil
+}
+
+func (a *app) close() {
+	a.server.DB.Close()
+}
+
+// lookupUser finds a user by id or email
+func (a *app) lookupUser(ctx context.Context, idOrEmail string) (*user.User, error) {
+	if idOrEmail == "" {
+		return nil, errors.New("--user is required")
+	}
+
+	var u *user.User
+	var err error
+	if id, parseErr := uuid.Parse(idOrEmail); parseErr == nil {
+		u, err = a.repos.User.GetUserByID(ctx, id)
+	} else {
+		u, err = a.repos.User.GetUserByEmail(ctx, idOrEmail)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, fmt.Errorf("user %s not found", idOrEmail)
+	}
+	return u, nil
+}
+
+// subcommand splits "<name> [flags]" and checks the name is one of names
+func subcommand(command string, args []string, names ...string) (string, []string, error) {
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("usage: jwt %s %s", command, strings.Join(names, "|"))
+	}
+	for _, name := range names {
+		if args[0] == name {
+			return name, args[1:], nil
+		}
+	}
+	return "", nil, fmt.Errorf("unknown %s command %q, expected %s", command, args[0], strings.Join(names, "|"))
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("jwt "+name, flag.ExitOnError)
+}
+
+// readPassword returns the password flag, or reads a line from standard input.
+// Typed passwords are not echoed; piped ones are read as they are.
+func readPassword(password string) (string, error) {
+	if password != "" {
+		return password, nil
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		typed, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil || len(typed) == 0 {
+			return "", errors.New("no password given")
+		}
+		return string(typed), nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("no password given")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/2SSK/jwt/internal/config"
+	utils "github.com/2SSK/jwt/internal/lib"
+)
+
+func runKeys(cfg *config.Config, args []string) error {
+	name, args, err := subcommand("keys", args, "generate", "rotate", "list")
+	if err != nil {
+		return err
+	}
+
+	dir := cfg.Tokens.SigningKeysDir
+	if dir == "" {
+		return errors.New("tokens.signing_keys_dir is not set")
+	}
+
+	flags := newFlagSet("keys " + name)
+	alg := "ES256"
+	keep := 2
+	if name != "list" {
+		flags.StringVar(&alg, "alg", alg, "key algorithm: ES256, RS256 or EdDSA")
+	}
+	if name == "rotate" {
+		flags.IntVar(&keep, "keep", keep, "keys to keep at least, including the new one; older keys are deleted once replaced for sessions.max_age")
+	}
+	_ = flags.Parse(args)
+
+	switch name {
+	case "generate", "rotate":
+		if name == "rotate" && keep < 2 {
+			// The previous key must outlive the tokens it signed
+			return errors.New("--keep must be at least 2")
+		}
+
+		key, err := utils.GenerateSigningKey(dir, alg)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("generated %s key %s in %s\n", key.Method.Alg(), key.ID, key.File)
+
+		if name == "rotate" {
+			// Refresh tokens live at most max_age, so keys replaced longer ago signed
+			// nothing still valid
+			deleted, err := utils.PruneSigningKeys(dir, keep, cfg.Sessions.MaxAge)
+			for _, file := range deleted {
+				fmt.Printf("deleted %s\n", file)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		fmt.Println("restart the servers to sign with the new key")
+		return nil
+	}
+
+	keys, err := utils.LoadSigningKeys(dir)
+	if err != nil {
+		return err
+	}
+	current := keys.Current()
+	for _, key := range keys.All() {
+		marker := " "
+		if key == current {
+			marker = "*"
+		}
+		fmt.Printf("%s %-6s %s  %s\n", marker, key.Method.Alg(), key.ID, key.File)
+	}
+	return nil
+}
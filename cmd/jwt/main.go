@@ -2,76 +2,71 @@ package main
 
 import (
 	"context"
-	"errors"
-	"net/http"
+	"fmt"
 	"os"
-	"os/signal"
-	"time"
 
 	"github.com/2SSK/jwt/internal/config"
-	"github.com/2SSK/jwt/internal/database"
-	"github.com/2SSK/jwt/internal/handler"
 	"github.com/2SSK/jwt/internal/logger"
-	"github.com/2SSK/jwt/internal/repository"
-	"github.com/2SSK/jwt/internal/router"
-	"github.com/2SSK/jwt/internal/server"
-	"github.com/2SSK/jwt/internal/service"
 )
 
 const DefaultContextTimeout = 30
 
+const usage = `Usage: jwt <command> [arguments]
+
+Commands:
+  serve                                start the server (the default)
+  migrate up|down|status               apply, revert or list database migrations
+    [--to <version>] [--dry-run]       stop at a version, or print the SQL instead
+  user create --email <email> [--admin] create a user
+  user set-role --user <id|email> --role <role>
+                                       change a user's role
+  user reset-password --user <id|email>
+                                       set a user's password and sign them out
+  keys generate|rotate|list            manage the token signing keys
+  sessions revoke --user <id|email>    sign a user out everywhere
+
+Passwords are read from standard input unless given with --password.
+Run "jwt <command> -h" for the arguments of a command.
+`
+
 func main() {
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return
+	}
+
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		panic("failed to load config: " + err.Error())
 	}
 
 	log := logger.NewLogger(cfg.Observability)
+	ctx := context.Background()
 
-	if cfg.Primary.Env != "local" {
-		if err := database.Migrate(context.Background(), &log, cfg); err != nil {
-			log.Fatal().Err(err).Msg("failed to migrate database")
-		}
+	switch command {
+	case "serve":
+		serve(cfg, &log)
+	case "migrate":
+		err = runMigrate(ctx, cfg, &log, args)
+	case "user":
+		err = runUser(ctx, cfg, &log, args)
+	case "keys":
+		err = runKeys(cfg, args)
+	case "sessions":
+		err = runSessions(ctx, cfg, &log, args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
 	}
 
-	// Initialize server
-	srv, err := server.New(cfg, &log)
 	if err != nil {
-		log.Fatal().Err(err).Msg("failed to initialize server")
-	}
-
-	// Initialize repositories, services, and handlers
-	repos := repository.NewRepositories(srv)
-	services, serviceErr := service.NewServices(srv, repos)
-	if serviceErr != nil {
-		log.Fatal().Err(serviceErr).Msg("could not create services")
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
 	}
-	handlers := handler.NewHandlers(srv, services)
-
-	// Initialize router
-	r := router.NewRouter(srv, handlers, services)
-
-	// Setup HTTP server
-	srv.SetupHTTPServer(r)
-
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
-
-	// Start server
-	go func() {
-		if err = srv.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Fatal().Err(err).Msg("failed to start server")
-		}
-	}()
-
-	// Wait for interrupt signal to gracefully shutdown the server
-	<-ctx.Done()
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultContextTimeout*time.Second)
-
-	if err = srv.Shutdown(ctx); err != nil {
-		log.Fatal().Err(err).Msg("server forced to shutdown")
-	}
-	stop()
-	cancel()
-
-	log.Info().Msg("server exited properly")
 }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/2SSK/jwt/internal/config"
+	"github.com/2SSK/jwt/internal/database"
+	"github.com/rs/zerolog"
+)
+
+func runMigrate(ctx context.Context, cfg *config.Config, log *zerolog.Logger, args []string) error {
+	name, args, err := subcommand("migrate", args, "up", "down", "status")
+	if err != nil {
+		return err
+	}
+
+	flags := newFlagSet("migrate " + name)
+	var to int
+	var dryRun bool
+	if name != "status" {
+		flags.IntVar(&to, "to", -1, "target version, by default the latest for up and one before the current for down")
+		flags.BoolVar(&dryRun, "dry-run", false, "print the SQL that would run without running it")
+	}
+	_ = flags.Parse(args)
+
+	m, err := database.NewMigrator(ctx, log, cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Close(ctx)
+
+	current, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	if name == "status" {
+		for _, migration := range m.Migrations() {
+			state := "pending"
+			if migration.Sequence <= current {
+				state = "applied"
+			}
+			fmt.Printf("%-8s %s\n", state, migration.Name)
+		}
+		fmt.Printf("schema version %d, latest %d\n", current, m.Latest())
+		return nil
+	}
+
+	target := int32(to)
+	switch {
+	case to < 0 && name == "up":
+		target = m.Latest()
+	case to < 0:
+		target = max(current-1, 0)
+	case name == "up" && target < current:
+		return fmt.Errorf("version %d is below the current %d, use migrate down", target, current)
+	case name == "down" && target > current:
+		return fmt.Errorf("version %d is above the current %d, use migrate up", target, current)
+	}
+
+	if !dryRun {
+		return m.MigrateTo(ctx, target)
+	}
+
+	steps, err := m.Plan(ctx, target)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		fmt.Printf("-- schema already at version %d\n", current)
+	}
+	for _, step := range steps {
+		fmt.Printf("-- %s (%s)\n%s\n\n", step.Name, step.Direction, step.SQL)
+	}
+	return nil
+}
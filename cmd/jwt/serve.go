@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/2SSK/jwt/internal/config"
+	"github.com/2SSK/jwt/internal/database"
+	"github.com/2SSK/jwt/internal/handler"
+	"github.com/2SSK/jwt/internal/repository"
+	"github.com/2SSK/jwt/internal/router"
+	"github.com/2SSK/jwt/internal/server"
+	"github.com/2SSK/jwt/internal/service"
+	"github.com/2SSK/jwt/internal/tracing"
+	"github.com/rs/zerolog"
+)
+
+func serve(cfg *config.Config, log *zerolog.Logger) {
+	if !cfg.Database.ManualMigrations {
+		if err := database.Migrate(context.Background(), log, cfg); err != nil {
+			log.Fatal().Err(err).Msg("failed to migrate database")
+		}
+	}
+	if err := database.CheckSchema(context.Background(), log, cfg); err != nil {
+		log.Fatal().Err(err).Msg("refusing to serve")
+	}
+
+	// Initialize tracing before anything that starts spans
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Observability, log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to set up tracing")
+	}
+
+	// Initialize server
+	srv, err := server.New(cfg, log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to initialize server")
+	}
+
+	// Initialize repositories, services, and handlers
+	repos := repository.NewRepositories(srv)
+	services, serviceErr := service.NewServices(srv, repos)
+	if serviceErr != nil {
+		log.Fatal().Err(serviceErr).Msg("could not create services")
+	}
+	handlers := handler.NewHandlers(srv, services)
+
+	// Initialize router
+	r := router.NewRouter(srv, handlers, services)
+
+	// Setup HTTP server
+	if err := srv.SetupHTTPServer(r); err != nil {
+		log.Fatal().Err(err).Msg("failed to setup HTTP server")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+
+	// Start background webhook delivery
+	go services.Webhook.RunDispatcher(ctx)
+
+	// Start background cleanup of expired opaque tokens
+	go services.Token.RunCleanup(ctx)
+
+	// Start server
+	go func() {
+		if err = srv.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal().Err(err).Msg("failed to start server")
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	<-ctx.Done()
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultContextTimeout*time.Second)
+
+	if err = srv.Shutdown(ctx); err != nil {
+		log.Fatal().Err(err).Msg("server forced to shutdown")
+	}
+	if err = shutdownTracing(ctx); err != nil {
+		log.Error().Err(err).Msg("failed to flush traces")
+	}
+	stop()
+	cancel()
+
+	log.Info().Msg("server exited properly")
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/2SSK/jwt/internal/config"
+	"github.com/rs/zerolog"
+)
+
+func runSessions(ctx context.Context, cfg *config.Config, log *zerolog.Logger, args []string) error {
+	_, args, err := subcommand("sessions", args, "revoke")
+	if err != nil {
+		return err
+	}
+
+	flags := newFlagSet("sessions revoke")
+	userRef := flags.String("user", "", "user id or email")
+	_ = flags.Parse(args)
+
+	a, err := newApp(cfg, log)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	u, err := a.lookupUser(ctx, *userRef)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := a.services.Session.RevokeAll(ctx, u.ID, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("revoked %d sessions of user %s\n", revoked.Revoked, u.ID)
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/2SSK/jwt/internal/config"
+	"github.com/2SSK/jwt/internal/model/user"
+	"github.com/rs/zerolog"
+)
+
+func runUser(ctx context.Context, cfg *config.Config, log *zerolog.Logger, args []string) error {
+	name, args, err := subcommand("user", args, "create", "set-role", "reset-password")
+	if err != nil {
+		return err
+	}
+
+	flags := newFlagSet("user " + name)
+	var payload user.AddUserPayload
+	var admin bool
+	var userRef, role, password string
+	switch name {
+	case "create":
+		flags.StringVar(&payload.Email, "email", "", "email address")
+		flags.StringVar(&payload.FirstName, "first-name", "", "first name")
+		flags.StringVar(&payload.LastName, "last-name", "", "last name")
+		flags.StringVar(&payload.Phone, "phone", "", "phone number")
+		flags.StringVar(&password, "password", "", "password, read from standard input when not given")
+		flags.BoolVar(&admin, "admin", false, "make the user an admin")
+	case "set-role":
+		flags.StringVar(&userRef, "user", "", "user id or email")
+		flags.StringVar(&role, "role", "", "new role: user or admin")
+	case "reset-password":
+		flags.StringVar(&userRef, "user", "", "user id or email")
+		flags.StringVar(&password, "password", "", "new password, read from standard input when not given")
+	}
+	_ = flags.Parse(args)
+
+	a, err := newApp(cfg, log)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	switch name {
+	case "create":
+		if admin {
+			payload.UserType = "admin"
+		}
+		if payload.Password, err = readPassword(password); err != nil {
+			return err
+		}
+		if err := payload.Validate(); err != nil {
+			return err
+		}
+
+		created, err := a.services.User.CreateUser(ctx, &payload)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("created %s user %s (%s)\n", *created.UserType, created.ID, *created.Email)
+
+	case "set-role":
+		u, err := a.lookupUser(ctx, userRef)
+		if err != nil {
+			return err
+		}
+		update := user.UpdateUserPayload{UserType: &role}
+		if err := update.Validate(); err != nil || role == "" {
+			return fmt.Errorf("--role must be user or admin")
+		}
+		if _, err := a.services.User.UpdateUser(ctx, u.ID, &update); err != nil {
+			return err
+		}
+		fmt.Printf("user %s is now %s\n", u.ID, role)
+
+	case "reset-password":
+		u, err := a.lookupUser(ctx, userRef)
+		if err != nil {
+			return err
+		}
+		newPassword, err := readPassword(password)
+		if err != nil {
+			return err
+		}
+		if err := a.services.User.ResetPassword(ctx, u.ID, &user.ResetPasswordPayload{NewPassword: newPassword}); err != nil {
+			return err
+		}
+		fmt.Printf("reset the password of user %s and signed them out\n", u.ID)
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/go-viper/mapstructure/v2"
 	_ "github.com/joho/godotenv/autoload"
 	"github.com/knadh/koanf/providers/env"
 	"github.com/knadh/koanf/v2"
@@ -12,11 +13,25 @@ import (
 )
 
 type Config struct {
-	Primary       Primary              `koanf:"primary" validate:"required"`
-	Server        ServerConfig         `koanf:"server" validate:"required"`
-	Database      DatabaseConfig       `koanf:"database" validate:"required"`
-	Auth          AuthConfig           `koanf:"auth" validate:"required"`
-	Observability *ObservabilityConfig `koanf:"observability"`
+	Primary         Primary                `koanf:"primary" validate:"required"`
+	Server          ServerConfig           `koanf:"server" validate:"required"`
+	TLS             *TLSConfig             `koanf:"tls"`
+	Database        DatabaseConfig         `koanf:"database" validate:"required"`
+	Auth            AuthConfig             `koanf:"auth" validate:"required"`
+	PasswordHashing *PasswordHashConfig    `koanf:"password_hashing"`
+	PasswordPolicy  *PasswordPolicyConfig  `koanf:"password_policy"`
+	Lockout         *LockoutConfig         `koanf:"lockout"`
+	Sessions        *SessionConfig         `koanf:"sessions"`
+	RefreshCookie   *RefreshCookieConfig   `koanf:"refresh_cookie"`
+	DPoP            *DPoPConfig            `koanf:"dpop"`
+	TokenEncryption *TokenEncryptionConfig `koanf:"token_encryption"`
+	Tokens          *TokensConfig          `koanf:"tokens"`
+	Webhooks        *WebhookConfig         `koanf:"webhooks"`
+	WebAuthn        *WebAuthnConfig        `koanf:"webauthn"`
+	MFA             *MFAConfig             `koanf:"mfa"`
+	Email           *EmailConfig           `koanf:"email"`
+	MagicLink       *MagicLinkConfig       `koanf:"magic_link"`
+	Observability   *ObservabilityConfig   `koanf:"observability"`
 }
 
 type Primary struct {
@@ -29,6 +44,11 @@ type ServerConfig struct {
 	WriteTimeout       int      `koanf:"write_timeout" validate:"required"`
 	IdleTimeout        int      `koanf:"idle_timeout" validate:"required"`
 	CORSAllowedOrigins []string `koanf:"cors_allowed_origins" validate:"required"`
+	// TrustedProxies are the CIDR ranges of the reverse proxies in front of the
+	// server, comma separated. Client addresses are read from X-Forwarded-For only
+	// when the request comes through one of them; without any, the address of the
+	// connection is used and forwarding headers are ignored.
+	TrustedProxies []string `koanf:"trusted_proxies" validate:"omitempty,dive,cidr"`
 }
 
 type DatabaseConfig struct {
@@ -39,13 +59,25 @@ type DatabaseConfig struct {
 	Name            string `koanf:"name" validate:"required"`
 	SSLMode         string `koanf:"ssl_mode" validate:"required"`
 	MaxOpenConns    int    `koanf:"max_open_conns" validate:"required"`
-	MaxIdleConns    int    `koanf:"max_idle_conns" validate:"required"`
+	MaxIdleConns    int    `koanf:"max_idle_conns"` // Deprecated: ignored, pgx pools have no idle cap; see MinIdleConns
 	ConnMaxLifetime int    `koanf:"conn_max_lifetime" validate:"required"`
 	ConnMaxIdleTime int    `koanf:"conn_max_idle_time" validate:"required"`
+	// MinIdleConns is how many connections each pool keeps open while idle, none
+	// by default; the rest close after ConnMaxIdleTime
+	MinIdleConns int `koanf:"min_idle_conns" validate:"gte=0,ltefield=MaxOpenConns"`
+	// ReplicaDSNs are connection strings of read replicas, comma separated, that
+	// serve reads which tolerate replication lag
+	ReplicaDSNs []string `koanf:"replica_dsns"`
+	// ManualMigrations stops the server migrating the database on startup. It
+	// then only checks the schema is current and migrations are run with
+	// "jwt migrate up" instead.
+	ManualMigrations bool `koanf:"manual_migrations"`
 }
 
 type AuthConfig struct {
 	SecretKey string `koanf:"secret_key" validate:"required"`
+	// MFAIssuer is the account issuer shown in authenticator apps
+	MFAIssuer string `koanf:"mfa_issuer"`
 }
 
 func parseMapString(value string) (map[string]string, bool) {
@@ -154,7 +186,16 @@ func LoadConfig() (*Config, error) {
 
 	mainConfig := &Config{}
 
-	err = k.Unmarshal("", mainConfig)
+	// Lists come from the environment comma separated
+	err = k.UnmarshalWithConf("", mainConfig, koanf.UnmarshalConf{
+		DecoderConfig: &mapstructure.DecoderConfig{
+			DecodeHook: mapstructure.ComposeDecodeHookFunc(
+				mapstructure.StringToTimeDurationHookFunc(),
+				mapstructure.StringToSliceHookFunc(","),
+				mapstructure.TextUnmarshallerHookFunc()),
+			WeaklyTypedInput: true,
+		},
+	})
 	if err != nil {
 		logger.Fatal().Err(err).Msg("could not unmarshal main config")
 	}
@@ -179,5 +220,121 @@ func LoadConfig() (*Config, error) {
 		logger.Fatal().Err(err).Msg("invalid observability config")
 	}
 
+	if mainConfig.Auth.MFAIssuer == "" {
+		mainConfig.Auth.MFAIssuer = "jwt"
+	}
+
+	if mainConfig.PasswordHashing == nil {
+		mainConfig.PasswordHashing = DefaultPasswordHashConfig()
+	}
+
+	if err := mainConfig.PasswordHashing.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid password hashing config")
+	}
+
+	if mainConfig.PasswordPolicy == nil {
+		mainConfig.PasswordPolicy = DefaultPasswordPolicyConfig()
+	}
+
+	if err := mainConfig.PasswordPolicy.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid password policy config")
+	}
+
+	if mainConfig.Lockout == nil {
+		mainConfig.Lockout = DefaultLockoutConfig()
+	}
+
+	if err := mainConfig.Lockout.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid lockout config")
+	}
+
+	if mainConfig.TLS == nil {
+		mainConfig.TLS = DefaultTLSConfig()
+	}
+
+	if err := mainConfig.TLS.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid tls config")
+	}
+
+	if mainConfig.Sessions == nil {
+		mainConfig.Sessions = DefaultSessionConfig()
+	}
+
+	if err := mainConfig.Sessions.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid sessions config")
+	}
+
+	if mainConfig.RefreshCookie == nil {
+		mainConfig.RefreshCookie = DefaultRefreshCookieConfig()
+	}
+
+	if err := mainConfig.RefreshCookie.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid refresh cookie config")
+	}
+
+	if mainConfig.DPoP == nil {
+		mainConfig.DPoP = DefaultDPoPConfig()
+	}
+
+	if err := mainConfig.DPoP.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid dpop config")
+	}
+
+	if mainConfig.TokenEncryption == nil {
+		mainConfig.TokenEncryption = DefaultTokenEncryptionConfig()
+	}
+
+	if err := mainConfig.TokenEncryption.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid token encryption config")
+	}
+
+	if mainConfig.Tokens == nil {
+		mainConfig.Tokens = DefaultTokensConfig()
+	}
+
+	if err := mainConfig.Tokens.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid tokens config")
+	}
+
+	if mainConfig.Webhooks == nil {
+		mainConfig.Webhooks = DefaultWebhookConfig()
+	}
+
+	if err := mainConfig.Webhooks.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid webhooks config")
+	}
+
+	if mainConfig.WebAuthn == nil {
+		mainConfig.WebAuthn = DefaultWebAuthnConfig()
+	}
+
+	if err := mainConfig.WebAuthn.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid webauthn config")
+	}
+
+	if mainConfig.MFA == nil {
+		mainConfig.MFA = DefaultMFAConfig()
+	}
+
+	if err := mainConfig.MFA.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid mfa config")
+	}
+
+	if mainConfig.Email == nil {
+		mainConfig.Email = DefaultEmailConfig()
+	}
+
+	if err := mainConfig.Email.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid email config")
+	}
+
+	if mainConfig.MagicLink == nil {
+		mainConfig.MagicLink = DefaultMagicLinkConfig()
+	}
+
+	if err := mainConfig.MagicLink.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid magic link config")
+	}
+
 	return mainConfig, nil
 }
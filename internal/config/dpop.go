@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// DPoPConfig controls sender-constrained tokens (RFC 9449). Clients that send a
+// DPoP proof when logging in get tokens bound to the proof's key, and every later
+// request with those tokens must carry a fresh proof signed by the same key.
+type DPoPConfig struct {
+	// Enabled allows binding new sessions; tokens already bound are always checked
+	Enabled bool `koanf:"enabled"`
+	// Algorithms lists the proof signature algorithms accepted
+	Algorithms []string `koanf:"algorithms"`
+	// ProofMaxAge is how old a proof's iat may be, which is also how long its jti is remembered
+	ProofMaxAge time.Duration `koanf:"proof_max_age"`
+	// ClockSkew tolerates client clocks running ahead
+	ClockSkew time.Duration `koanf:"clock_skew"`
+}
+
+// dpopAlgorithms are the asymmetric algorithms a proof can be signed with
+var dpopAlgorithms = []string{"ES256", "ES384", "ES512", "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "EdDSA"}
+
+func DefaultDPoPConfig() *DPoPConfig {
+	return &DPoPConfig{
+		Enabled:     true,
+		Algorithms:  []string{"ES256", "RS256", "PS256", "EdDSA"},
+		ProofMaxAge: time.Minute,
+		ClockSkew:   10 * time.Second,
+	}
+}
+
+func (c *DPoPConfig) Validate() error {
+	if len(c.Algorithms) == 0 {
+		return fmt.Errorf("dpop algorithms must not be empty")
+	}
+	for _, alg := range c.Algorithms {
+		if !slices.Contains(dpopAlgorithms, alg) {
+			return fmt.Errorf("dpop algorithm %q is not supported", alg)
+		}
+	}
+	if c.ProofMaxAge <= 0 {
+		return fmt.Errorf("dpop proof_max_age must be positive")
+	}
+	if c.ClockSkew < 0 {
+		return fmt.Errorf("dpop clock_skew must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import "fmt"
+
+const (
+	EmailDriverSMTP = "smtp"
+	EmailDriverLog  = "log"
+)
+
+type EmailConfig struct {
+	// Driver is "smtp" to deliver mail, or "log" to write messages to the application log
+	Driver       string `koanf:"driver"`
+	From         string `koanf:"from"`
+	SMTPHost     string `koanf:"smtp_host"`
+	SMTPPort     int    `koanf:"smtp_port"`
+	SMTPUsername string `koanf:"smtp_username"`
+	SMTPPassword string `koanf:"smtp_password"`
+}
+
+func DefaultEmailConfig() *EmailConfig {
+	return &EmailConfig{
+		Driver:   EmailDriverLog,
+		From:     "no-reply@localhost",
+		SMTPPort: 587,
+	}
+}
+
+func (c *EmailConfig) Validate() error {
+	switch c.Driver {
+	case EmailDriverLog:
+	case EmailDriverSMTP:
+		if c.SMTPHost == "" {
+			return fmt.Errorf("email smtp_host is required for the smtp driver")
+		}
+		if c.SMTPPort <= 0 {
+			return fmt.Errorf("email smtp_port must be positive")
+		}
+	default:
+		return fmt.Errorf("email driver must be %q or %q", EmailDriverSMTP, EmailDriverLog)
+	}
+	if c.From == "" {
+		return fmt.Errorf("email from is required")
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// LockoutConfig throttles password logins. Failures are counted per account and per
+// client IP; after DelayAfter failures each further attempt must wait an exponentially
+// growing delay, and reaching a threshold locks the account or IP out entirely.
+type LockoutConfig struct {
+	Enabled bool `koanf:"enabled"`
+	// FailureWindow is how long a failure is remembered; counters restart after a quiet period this long
+	FailureWindow          time.Duration `koanf:"failure_window"`
+	DelayAfter             int           `koanf:"delay_after"`
+	BaseDelay              time.Duration `koanf:"base_delay"`
+	MaxDelay               time.Duration `koanf:"max_delay"`
+	AccountThreshold       int           `koanf:"account_threshold"`
+	AccountLockoutDuration time.Duration `koanf:"account_lockout_duration"`
+	IPThreshold            int           `koanf:"ip_threshold"`
+	IPLockoutDuration      time.Duration `koanf:"ip_lockout_duration"`
+}
+
+func DefaultLockoutConfig() *LockoutConfig {
+	return &LockoutConfig{
+		Enabled:                true,
+		FailureWindow:          15 * time.Minute,
+		DelayAfter:             3,
+		BaseDelay:              time.Second,
+		MaxDelay:               30 * time.Second,
+		AccountThreshold:       10,
+		AccountLockoutDuration: 15 * time.Minute,
+		IPThreshold:            100,
+		IPLockoutDuration:      15 * time.Minute,
+	}
+}
+
+func (c *LockoutConfig) Validate() error {
+	if c.FailureWindow <= 0 {
+		return fmt.Errorf("lockout failure_window must be positive")
+	}
+	if c.DelayAfter <= 0 {
+		return fmt.Errorf("lockout delay_after must be positive")
+	}
+	if c.BaseDelay <= 0 || c.MaxDelay < c.BaseDelay {
+		return fmt.Errorf("lockout delays must satisfy 0 < base_delay <= max_delay")
+	}
+	if c.AccountThreshold <= c.DelayAfter {
+		return fmt.Errorf("lockout account_threshold must be greater than delay_after")
+	}
+	if c.IPThreshold <= c.DelayAfter {
+		return fmt.Errorf("lockout ip_threshold must be greater than delay_after")
+	}
+	if c.AccountLockoutDuration <= 0 || c.IPLockoutDuration <= 0 {
+		return fmt.Errorf("lockout durations must be positive")
+	}
+	return nil
+}
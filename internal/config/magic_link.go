@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+type MagicLinkConfig struct {
+	// LinkURL is the frontend page that receives the token as a "token" query parameter
+	LinkURL     string        `koanf:"link_url"`
+	TTL         time.Duration `koanf:"ttl"`
+	MaxAttempts int           `koanf:"max_attempts"`
+	// MaxPerHour caps how many links a single account can be sent in an hour
+	MaxPerHour int `koanf:"max_per_hour"`
+}
+
+func DefaultMagicLinkConfig() *MagicLinkConfig {
+	return &MagicLinkConfig{
+		LinkURL:     "http://localhost:8080/login/magic-link",
+		TTL:         15 * time.Minute,
+		MaxAttempts: 5,
+		MaxPerHour:  5,
+	}
+}
+
+func (c *MagicLinkConfig) Validate() error {
+	if c.LinkURL == "" {
+		return fmt.Errorf("magic_link link_url is required")
+	}
+	if c.TTL <= 0 {
+		return fmt.Errorf("magic_link ttl must be positive")
+	}
+	if c.MaxAttempts <= 0 {
+		return fmt.Errorf("magic_link max_attempts must be positive")
+	}
+	if c.MaxPerHour <= 0 {
+		return fmt.Errorf("magic_link max_per_hour must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// MFAConfig holds the keys TOTP secrets are encrypted with at rest. They are kept
+// apart from auth.secret_key so that rotating the token secret leaves enrolled
+// authenticators working.
+type MFAConfig struct {
+	// EncryptionKey is the base64 encoded 256-bit key new secrets are encrypted with
+	EncryptionKey string `koanf:"encryption_key"`
+	// EncryptionKeyID is stored next to each secret to tell which key sealed it,
+	// "1" when not set
+	EncryptionKeyID string `koanf:"encryption_key_id"`
+	// RetiredKeys are earlier keys by id, still used to decrypt secrets sealed with
+	// them. Secrets are re-encrypted with the current key when next used.
+	RetiredKeys map[string]string `koanf:"retired_keys"`
+}
+
+func DefaultMFAConfig() *MFAConfig {
+	return &MFAConfig{}
+}
+
+// KeyID returns the id of the key new secrets are encrypted with
+func (c *MFAConfig) KeyID() string {
+	if c.EncryptionKeyID == "" {
+		return "1"
+	}
+	return c.EncryptionKeyID
+}
+
+// Key returns the decryption key for a key id
+func (c *MFAConfig) Key(id string) ([]byte, bool) {
+	encoded, ok := c.RetiredKeys[id]
+	if id == c.KeyID() {
+		encoded, ok = c.EncryptionKey, true
+	}
+	if !ok {
+		return nil, false
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		return nil, false
+	}
+	return key, true
+}
+
+func (c *MFAConfig) Validate() error {
+	if c.EncryptionKey == "" {
+		return fmt.Errorf("mfa encryption_key is required")
+	}
+	if _, ok := c.Key(c.KeyID()); !ok {
+		return fmt.Errorf("mfa encryption_key must be 32 base64 encoded bytes")
+	}
+	for id := range c.RetiredKeys {
+		if id == c.KeyID() {
+			return fmt.Errorf("mfa retired_keys.%s reuses the id of the current key", id)
+		}
+		if _, ok := c.Key(id); !ok {
+			return fmt.Errorf("mfa retired_keys.%s must be 32 base64 encoded bytes", id)
+		}
+	}
+	return nil
+}
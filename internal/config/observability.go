@@ -10,6 +10,8 @@ type ObservabilityConfig struct {
 	Environment  string             `koanf:"environment" validate:"required"`
 	Logging      LoggingConfig      `koanf:"logging" validate:"required"`
 	HealthChecks HealthChecksConfig `koanf:"health_checks" validate:"required"`
+	Metrics      MetricsConfig      `koanf:"metrics"`
+	Tracing      TracingConfig      `koanf:"tracing"`
 }
 
 type LoggingConfig struct {
@@ -18,6 +20,32 @@ type LoggingConfig struct {
 	SlowQueryThreshold time.Duration `koanf:"slow_query_threshold"`
 }
 
+// MetricsConfig exposes Prometheus metrics at /metrics, on the API port or, when
+// Port is set, on a separate admin port kept off the public network. They are off
+// by default, as the metrics reveal routes and traffic to anyone who can reach them.
+type MetricsConfig struct {
+	Enabled bool   `koanf:"enabled"`
+	Port    string `koanf:"port"`
+}
+
+// TracingConfig exports OpenTelemetry traces over OTLP/HTTP to Endpoint, a
+// collector's host:port, or prints them to stdout
+type TracingConfig struct {
+	Enabled  bool   `koanf:"enabled"`
+	Exporter string `koanf:"exporter"`
+	Endpoint string `koanf:"endpoint"`
+	// Insecure sends OTLP over plain HTTP, as to a collector on localhost
+	Insecure bool `koanf:"insecure"`
+	// SampleRatio is the share of new traces recorded. Requests arriving with a
+	// traceparent follow the caller's sampling decision.
+	SampleRatio float64 `koanf:"sample_ratio"`
+}
+
+const (
+	TracingExporterOTLP   = "otlp"
+	TracingExporterStdout = "stdout"
+)
+
 type HealthChecksConfig struct {
 	Enabled  bool          `koanf:"enabled"`
 	Interval time.Duration `koanf:"interval" validate:"min=1s"`
@@ -40,6 +68,12 @@ func DefaultObservabilityConfig() *ObservabilityConfig {
 			Timeout:  5 * time.Second,
 			Checks:   []string{"database", "redis"},
 		},
+		Tracing: TracingConfig{
+			Exporter:    TracingExporterOTLP,
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			SampleRatio: 1,
+		},
 	}
 }
 
@@ -56,6 +90,14 @@ func (c *ObservabilityConfig) Validate() error {
 	if c.Logging.SlowQueryThreshold < 0 {
 		return fmt.Errorf("logging slow_query_threshold must be non-negative")
 	}
+	if c.Tracing.Enabled {
+		if c.Tracing.Exporter != TracingExporterOTLP && c.Tracing.Exporter != TracingExporterStdout {
+			return fmt.Errorf("tracing exporter must be %s or %s", TracingExporterOTLP, TracingExporterStdout)
+		}
+		if c.Tracing.SampleRatio <= 0 || c.Tracing.SampleRatio > 1 {
+			return fmt.Errorf("tracing sample_ratio must be above 0 and at most 1")
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,105 @@
+package config
+
+import "fmt"
+
+const (
+	PasswordAlgorithmArgon2id = "argon2id"
+	PasswordAlgorithmBcrypt   = "bcrypt"
+)
+
+// PasswordHashConfig selects the algorithm for new hashes. Hashes made with other
+// algorithms or parameters still verify and are upgraded on the next login.
+type PasswordHashConfig struct {
+	Algorithm string `koanf:"algorithm"`
+	// Argon2Memory is in KiB
+	Argon2Memory      uint32 `koanf:"argon2_memory"`
+	Argon2Iterations  uint32 `koanf:"argon2_iterations"`
+	Argon2Parallelism uint8  `koanf:"argon2_parallelism"`
+	Argon2SaltLength  uint32 `koanf:"argon2_salt_length"`
+	Argon2KeyLength   uint32 `koanf:"argon2_key_length"`
+	BcryptCost        int    `koanf:"bcrypt_cost"`
+}
+
+func DefaultPasswordHashConfig() *PasswordHashConfig {
+	return &PasswordHashConfig{
+		Algorithm:         PasswordAlgorithmArgon2id,
+		Argon2Memory:      64 * 1024,
+		Argon2Iterations:  3,
+		Argon2Parallelism: 4,
+		Argon2SaltLength:  16,
+		Argon2KeyLength:   32,
+		BcryptCost:        12,
+	}
+}
+
+func (c *PasswordHashConfig) Validate() error {
+	switch c.Algorithm {
+	case PasswordAlgorithmArgon2id:
+		if c.Argon2Memory < 8*uint32(c.Argon2Parallelism) {
+			return fmt.Errorf("password_hashing argon2_memory must be at least 8 KiB per lane")
+		}
+		if c.Argon2Iterations == 0 {
+			return fmt.Errorf("password_hashing argon2_iterations must be positive")
+		}
+		if c.Argon2Parallelism == 0 {
+			return fmt.Errorf("password_hashing argon2_parallelism must be positive")
+		}
+		if c.Argon2SaltLength < 8 {
+			return fmt.Errorf("password_hashing argon2_salt_length must be at least 8")
+		}
+		if c.Argon2KeyLength < 16 {
+			return fmt.Errorf("password_hashing argon2_key_length must be at least 16")
+		}
+	case PasswordAlgorithmBcrypt:
+		if c.BcryptCost < 4 || c.BcryptCost > 31 {
+			return fmt.Errorf("password_hashing bcrypt_cost must be between 4 and 31")
+		}
+	default:
+		return fmt.Errorf("password_hashing algorithm must be %q or %q", PasswordAlgorithmArgon2id, PasswordAlgorithmBcrypt)
+	}
+	return nil
+}
+
+type PasswordPolicyConfig struct {
+	MinLength     int  `koanf:"min_length"`
+	MaxLength     int  `koanf:"max_length"`
+	RequireUpper  bool `koanf:"require_upper"`
+	RequireLower  bool `koanf:"require_lower"`
+	RequireDigit  bool `koanf:"require_digit"`
+	RequireSymbol bool `koanf:"require_symbol"`
+	// DisallowPersonalInfo rejects passwords containing the user's email local part or names
+	DisallowPersonalInfo bool `koanf:"disallow_personal_info"`
+	// HistorySize is how many previous passwords cannot be reused, 0 disables the check
+	HistorySize int `koanf:"history_size"`
+	// BreachedListPath points to a SHA-1 password list in the HIBP download format,
+	// one "HASH:COUNT" per line sorted by hash. Empty disables the check.
+	BreachedListPath string `koanf:"breached_list_path"`
+	// BreachedMinCount is how many times a password must appear in the list to be rejected
+	BreachedMinCount int `koanf:"breached_min_count"`
+}
+
+func DefaultPasswordPolicyConfig() *PasswordPolicyConfig {
+	return &PasswordPolicyConfig{
+		MinLength:            8,
+		MaxLength:            128,
+		DisallowPersonalInfo: true,
+		HistorySize:          5,
+		BreachedMinCount:     1,
+	}
+}
+
+func (c *PasswordPolicyConfig) Validate() error {
+	if c.MinLength <= 0 {
+		return fmt.Errorf("password_policy min_length must be positive")
+	}
+	if c.MaxLength < c.MinLength {
+		return fmt.Errorf("password_policy max_length must not be less than min_length")
+	}
+	if c.HistorySize < 0 {
+		return fmt.Errorf("password_policy history_size must not be negative")
+	}
+	if c.BreachedMinCount <= 0 {
+		return fmt.Errorf("password_policy breached_min_count must be positive")
+	}
+	return nil
+}
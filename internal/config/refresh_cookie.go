@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// RefreshCookieConfig describes the cookies used for browser clients, which get
+// their refresh token as an HttpOnly cookie scoped to the refresh endpoint instead
+// of in the response body. A readable CSRF cookie carries the token they must echo
+// in CSRFHeader when refreshing.
+type RefreshCookieConfig struct {
+	Name           string `koanf:"name"`
+	Path           string `koanf:"path"`
+	Domain         string `koanf:"domain"`
+	Secure         bool   `koanf:"secure"`
+	SameSite       string `koanf:"same_site"`
+	CSRFCookieName string `koanf:"csrf_cookie_name"`
+	CSRFHeader     string `koanf:"csrf_header"`
+}
+
+func DefaultRefreshCookieConfig() *RefreshCookieConfig {
+	return &RefreshCookieConfig{
+		Name:           "refresh_token",
+		Path:           "/api/v1/auth/refresh",
+		Secure:         true,
+		SameSite:       "strict",
+		CSRFCookieName: "csrf_token",
+		CSRFHeader:     "X-CSRF-Token",
+	}
+}
+
+// SameSiteMode maps SameSite to its net/http value
+func (c *RefreshCookieConfig) SameSiteMode() http.SameSite {
+	switch strings.ToLower(c.SameSite) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteStrictMode
+	}
+}
+
+func (c *RefreshCookieConfig) Validate() error {
+	if c.Name == "" || c.CSRFCookieName == "" || c.CSRFHeader == "" {
+		return fmt.Errorf("refresh_cookie name, csrf_cookie_name and csrf_header are required")
+	}
+	if c.Name == c.CSRFCookieName {
+		return fmt.Errorf("refresh_cookie name and csrf_cookie_name must differ")
+	}
+	if !strings.HasPrefix(c.Path, "/") {
+		return fmt.Errorf("refresh_cookie path must start with /")
+	}
+	switch strings.ToLower(c.SameSite) {
+	case "strict", "lax":
+	case "none":
+		if !c.Secure {
+			return fmt.Errorf("refresh_cookie same_site none requires secure")
+		}
+	default:
+		return fmt.Errorf("refresh_cookie same_site must be one of strict, lax, none")
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// SessionConfig controls token and session lifetimes. A session expires when it
+// has not been refreshed for RefreshTTL (each refresh slides the window) or once
+// it is MaxAge old, whichever comes first.
+type SessionConfig struct {
+	AccessTTL  time.Duration `koanf:"access_ttl"`
+	RefreshTTL time.Duration `koanf:"refresh_ttl"`
+	// MaxAge is the absolute session lifetime; the user must log in again afterwards
+	MaxAge time.Duration `koanf:"max_age"`
+	// MaxPerUser caps concurrent sessions, the oldest is signed out when a new one
+	// would exceed it. Zero means no limit.
+	MaxPerUser int `koanf:"max_per_user"`
+	// StepUpMaxAge and StepUpLevel guard sensitive routes: the user must have logged
+	// in or re-authenticated this recently, at this level (1 single, 2 multi-factor)
+	StepUpMaxAge time.Duration `koanf:"step_up_max_age"`
+	StepUpLevel  int           `koanf:"step_up_level"`
+	// Roles and Clients override the lifetimes by user type and by the X-Client-Id
+	// sent at login. When both apply the shorter lifetime wins. The client id is
+	// not authenticated, so client overrides may only shorten the lifetimes.
+	Roles   map[string]TokenLifetime `koanf:"roles"`
+	Clients map[string]TokenLifetime `koanf:"clients"`
+}
+
+// TokenLifetime overrides the default TTLs, zero values inherit them
+type TokenLifetime struct {
+	AccessTTL  time.Duration `koanf:"access_ttl"`
+	RefreshTTL time.Duration `koanf:"refresh_ttl"`
+}
+
+func DefaultSessionConfig() *SessionConfig {
+	return &SessionConfig{
+		AccessTTL:    15 * time.Minute,
+		RefreshTTL:   7 * 24 * time.Hour,
+		MaxAge:       30 * 24 * time.Hour,
+		StepUpMaxAge: 10 * time.Minute,
+		StepUpLevel:  1,
+	}
+}
+
+// Lifetime resolves the TTLs for a user type and client id, either may be empty.
+// Client overrides longer than the role's or default TTLs are ignored.
+func (c *SessionConfig) Lifetime(role, clientID string) TokenLifetime {
+	roleLifetime, clientLifetime := c.Roles[role], c.Clients[clientID]
+
+	access := overrideOr(c.AccessTTL, roleLifetime.AccessTTL)
+	refresh := overrideOr(c.RefreshTTL, roleLifetime.RefreshTTL)
+
+	return TokenLifetime{
+		AccessTTL:  min(access, overrideOr(access, clientLifetime.AccessTTL)),
+		RefreshTTL: min(refresh, overrideOr(refresh, clientLifetime.RefreshTTL)),
+	}
+}
+
+// overrideOr returns the override, or def when it is not set
+func overrideOr(def, override time.Duration) time.Duration {
+	if override > 0 {
+		return override
+	}
+	return def
+}
+
+func (c *SessionConfig) Validate() error {
+	if c.AccessTTL <= 0 || c.RefreshTTL <= 0 {
+		return fmt.Errorf("sessions access_ttl and refresh_ttl must be positive")
+	}
+	if c.AccessTTL > c.RefreshTTL {
+		return fmt.Errorf("sessions access_ttl must not exceed refresh_ttl")
+	}
+	if c.MaxAge < c.RefreshTTL {
+		return fmt.Errorf("sessions max_age must be at least refresh_ttl")
+	}
+	if c.MaxPerUser < 0 {
+		return fmt.Errorf("sessions max_per_user must not be negative")
+	}
+	if c.StepUpMaxAge <= 0 {
+		return fmt.Errorf("sessions step_up_max_age must be positive")
+	}
+	if c.StepUpLevel < 1 || c.StepUpLevel > 2 {
+		return fmt.Errorf("sessions step_up_level must be 1 or 2")
+	}
+
+	for kind, overrides := range map[string]map[string]TokenLifetime{"roles": c.Roles, "clients": c.Clients} {
+		for name, o := range overrides {
+			if o.AccessTTL < 0 || o.RefreshTTL < 0 {
+				return fmt.Errorf("sessions %s.%s TTLs must not be negative", kind, name)
+			}
+			if o.RefreshTTL > c.MaxAge {
+				return fmt.Errorf("sessions %s.%s refresh_ttl must not exceed max_age", kind, name)
+			}
+		}
+	}
+	for name, o := range c.Clients {
+		if o.AccessTTL > c.AccessTTL || o.RefreshTTL > c.RefreshTTL {
+			return fmt.Errorf("sessions clients.%s TTLs must not exceed access_ttl and refresh_ttl", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// TLSConfig makes the server terminate TLS itself, optionally verifying client
+// certificates (RFC 8705). Tokens issued over a connection with a verified client
+// certificate are bound to it, which only works when no proxy terminates TLS in
+// front of the server.
+type TLSConfig struct {
+	Enabled  bool   `koanf:"enabled"`
+	CertFile string `koanf:"cert_file"`
+	KeyFile  string `koanf:"key_file"`
+	// MinVersion is "1.2" or "1.3"
+	MinVersion string `koanf:"min_version"`
+	// ClientCAFile holds the PEM CAs client certificates are verified against;
+	// client certificates are not requested without it
+	ClientCAFile string `koanf:"client_ca_file"`
+	// RequireClientCert rejects handshakes without a valid client certificate
+	// instead of only verifying the ones that are sent
+	RequireClientCert bool `koanf:"require_client_cert"`
+	// Clients registers the clients (by X-Client-Id) that authenticate with
+	// tls_client_auth, and the certificate each must present
+	Clients map[string]TLSClientAuth `koanf:"clients"`
+}
+
+// TLSClientAuth identifies a client's certificate by exactly one of its subject
+// DN (as printed in RFC 2253 form, e.g. "CN=billing,O=Example") or a subject
+// alternative name, like the tls_client_auth_* client metadata of RFC 8705
+type TLSClientAuth struct {
+	SubjectDN string `koanf:"subject_dn"`
+	SANDNS    string `koanf:"san_dns"`
+	SANURI    string `koanf:"san_uri"`
+	SANIP     string `koanf:"san_ip"`
+	SANEmail  string `koanf:"san_email"`
+}
+
+func DefaultTLSConfig() *TLSConfig {
+	return &TLSConfig{
+		MinVersion: "1.2",
+	}
+}
+
+// TLSMinVersion maps MinVersion to its crypto/tls value
+func (c *TLSConfig) TLSMinVersion() uint16 {
+	if c.MinVersion == "1.3" {
+		return tls.VersionTLS13
+	}
+	return tls.VersionTLS12
+}
+
+func (c *TLSConfig) Validate() error {
+	if c.MinVersion != "1.2" && c.MinVersion != "1.3" {
+		return fmt.Errorf("tls min_version must be 1.2 or 1.3")
+	}
+	if !c.Enabled {
+		if len(c.Clients) > 0 {
+			return fmt.Errorf("tls clients require tls to be enabled")
+		}
+		return nil
+	}
+	if c.CertFile == "" || c.KeyFile == "" {
+		return fmt.Errorf("tls cert_file and key_file are required")
+	}
+	if c.ClientCAFile == "" && (c.RequireClientCert || len(c.Clients) > 0) {
+		return fmt.Errorf("tls client_ca_file is required to verify client certificates")
+	}
+	for id, client := range c.Clients {
+		set := 0
+		for _, v := range []string{client.SubjectDN, client.SANDNS, client.SANURI, client.SANIP, client.SANEmail} {
+			if v != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			return fmt.Errorf("tls client %q must set exactly one of subject_dn, san_dns, san_uri, san_ip, san_email", id)
+		}
+	}
+	return nil
+}
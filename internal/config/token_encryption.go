@@ -0,0 +1,50 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// Key management algorithms for encrypted tokens
+const (
+	TokenEncryptionDirect     = "dir"
+	TokenEncryptionRSAOAEP256 = "RSA-OAEP-256"
+)
+
+// TokenEncryptionConfig wraps signed tokens in a JWE (nested JWT, RFC 7519 section
+// 5.2) so clients cannot read their claims. Content is always encrypted with
+// A256GCM; the key is either used directly or wrapped with RSA-OAEP-256.
+type TokenEncryptionConfig struct {
+	Enabled   bool   `koanf:"enabled"`
+	Algorithm string `koanf:"algorithm"`
+	// Key is the base64 encoded 256-bit key for "dir"
+	Key string `koanf:"key"`
+	// PrivateKeyFile is the PEM RSA key for "RSA-OAEP-256", at least 2048 bits
+	PrivateKeyFile string `koanf:"private_key_file"`
+}
+
+func DefaultTokenEncryptionConfig() *TokenEncryptionConfig {
+	return &TokenEncryptionConfig{
+		Algorithm: TokenEncryptionDirect,
+	}
+}
+
+func (c *TokenEncryptionConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	switch c.Algorithm {
+	case TokenEncryptionDirect:
+		key, err := base64.StdEncoding.DecodeString(c.Key)
+		if err != nil || len(key) != 32 {
+			return fmt.Errorf("token_encryption key must be 32 base64 encoded bytes")
+		}
+	case TokenEncryptionRSAOAEP256:
+		if c.PrivateKeyFile == "" {
+			return fmt.Errorf("token_encryption private_key_file is required for %s", TokenEncryptionRSAOAEP256)
+		}
+	default:
+		return fmt.Errorf("token_encryption algorithm must be %s or %s", TokenEncryptionDirect, TokenEncryptionRSAOAEP256)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Token formats
+const (
+	// TokenFormatJWT tokens are signed JWTs, optionally encrypted (see TokenEncryptionConfig)
+	TokenFormatJWT = "jwt"
+	// TokenFormatOpaque tokens are random references to claims kept in the database
+	TokenFormatOpaque = "opaque"
+	// TokenFormatPasetoPublic tokens are PASETO v4.public, signed with Ed25519
+	TokenFormatPasetoPublic = "paseto-v4-public"
+	// TokenFormatPasetoLocal tokens are PASETO v4.local, encrypted with a shared key
+	TokenFormatPasetoLocal = "paseto-v4-local"
+)
+
+var tokenFormats = []string{TokenFormatJWT, TokenFormatOpaque, TokenFormatPasetoPublic, TokenFormatPasetoLocal}
+
+// TokensConfig selects the format of issued tokens. JWTs and PASETOs are
+// self-contained; opaque reference tokens carry no data and are resolved against
+// the database, with the results cached in memory for CacheTTL. Tokens of every
+// format are accepted whatever the format issued.
+type TokensConfig struct {
+	Format string `koanf:"format"`
+	// Issuer and Audience, when set, become the iss and aud claims of every token
+	Issuer   string   `koanf:"issuer"`
+	Audience []string `koanf:"audience"`
+	// SigningKeysDir holds the PKCS#8 PEM keys JWTs are signed with, published at
+	// /.well-known/jwks.json. Without keys JWTs are signed with auth.secret_key.
+	SigningKeysDir string `koanf:"signing_keys_dir"`
+	// Clients overrides Format by the X-Client-Id sent at login
+	Clients   map[string]string `koanf:"clients"`
+	CacheTTL  time.Duration     `koanf:"cache_ttl"`
+	CacheSize int               `koanf:"cache_size"`
+	// CleanupInterval is how often expired opaque tokens are deleted
+	CleanupInterval time.Duration `koanf:"cleanup_interval"`
+	// IntrospectionClients lists the tls_client_auth clients (see TLSConfig.Clients)
+	// allowed to introspect tokens, besides admins
+	IntrospectionClients []string `koanf:"introspection_clients"`
+	// PasetoPrivateKeyFile is the PKCS#8 PEM Ed25519 key for paseto-v4-public
+	PasetoPrivateKeyFile string `koanf:"paseto_private_key_file"`
+	// PasetoLocalKey is the base64 encoded 256-bit key for paseto-v4-local
+	PasetoLocalKey string `koanf:"paseto_local_key"`
+}
+
+func DefaultTokensConfig() *TokensConfig {
+	return &TokensConfig{
+		Format:          TokenFormatJWT,
+		CacheTTL:        time.Minute,
+		CacheSize:       10000,
+		CleanupInterval: time.Hour,
+	}
+}
+
+// FormatFor returns the token format for a client id, which may be empty
+func (c *TokensConfig) FormatFor(clientID string) string {
+	if format, ok := c.Clients[clientID]; ok {
+		return format
+	}
+	return c.Format
+}
+
+// Uses reports whether any client is issued tokens of the format
+func (c *TokensConfig) Uses(format string) bool {
+	if c.Format == format {
+		return true
+	}
+	for _, clientFormat := range c.Clients {
+		if clientFormat == format {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *TokensConfig) Validate() error {
+	if !slices.Contains(tokenFormats, c.Format) {
+		return fmt.Errorf("tokens format must be one of %v", tokenFormats)
+	}
+	for id, format := range c.Clients {
+		if !slices.Contains(tokenFormats, format) {
+			return fmt.Errorf("tokens clients.%s must be one of %v", id, tokenFormats)
+		}
+	}
+	if c.CacheTTL < 0 || c.CacheSize < 0 {
+		return fmt.Errorf("tokens cache_ttl and cache_size must not be negative")
+	}
+	if c.CleanupInterval <= 0 {
+		return fmt.Errorf("tokens cleanup_interval must be positive")
+	}
+	if c.Uses(TokenFormatPasetoPublic) && c.PasetoPrivateKeyFile == "" {
+		return fmt.Errorf("tokens paseto_private_key_file is required for %s", TokenFormatPasetoPublic)
+	}
+	if c.PasetoLocalKey != "" || c.Uses(TokenFormatPasetoLocal) {
+		key, err := base64.StdEncoding.DecodeString(c.PasetoLocalKey)
+		if err != nil || len(key) != 32 {
+			return fmt.Errorf("tokens paseto_local_key must be 32 base64 encoded bytes")
+		}
+	}
+	return nil
+}
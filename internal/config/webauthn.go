@@ -0,0 +1,36 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+type WebAuthnConfig struct {
+	// RPID is the relying party id, normally the site's registrable domain without scheme or port
+	RPID          string        `koanf:"rp_id"`
+	RPDisplayName string        `koanf:"rp_display_name"`
+	RPOrigins     []string      `koanf:"rp_origins"`
+	Timeout       time.Duration `koanf:"timeout"`
+}
+
+func DefaultWebAuthnConfig() *WebAuthnConfig {
+	return &WebAuthnConfig{
+		RPID:          "localhost",
+		RPDisplayName: "jwt",
+		RPOrigins:     []string{"http://localhost:8080"},
+		Timeout:       5 * time.Minute,
+	}
+}
+
+func (c *WebAuthnConfig) Validate() error {
+	if c.RPID == "" {
+		return fmt.Errorf("webauthn rp_id is required")
+	}
+	if len(c.RPOrigins) == 0 {
+		return fmt.Errorf("webauthn rp_origins must contain at least one origin")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("webauthn timeout must be positive")
+	}
+	return nil
+}
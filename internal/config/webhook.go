@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+type WebhookConfig struct {
+	Enabled              bool          `koanf:"enabled"`
+	PollInterval         time.Duration `koanf:"poll_interval"`
+	RequestTimeout       time.Duration `koanf:"request_timeout"`
+	BatchSize            int           `koanf:"batch_size"`
+	MaxAttempts          int           `koanf:"max_attempts"`
+	InitialBackoff       time.Duration `koanf:"initial_backoff"`
+	MaxBackoff           time.Duration `koanf:"max_backoff"`
+	DisableAfterFailures int           `koanf:"disable_after_failures"`
+}
+
+func DefaultWebhookConfig() *WebhookConfig {
+	return &WebhookConfig{
+		Enabled:              true,
+		PollInterval:         5 * time.Second,
+		RequestTimeout:       10 * time.Second,
+		BatchSize:            20,
+		MaxAttempts:          8,
+		InitialBackoff:       30 * time.Second,
+		MaxBackoff:           6 * time.Hour,
+		DisableAfterFailures: 20,
+	}
+}
+
+func (c *WebhookConfig) Validate() error {
+	if c.PollInterval <= 0 {
+		return fmt.Errorf("webhooks poll_interval must be positive")
+	}
+	if c.RequestTimeout <= 0 {
+		return fmt.Errorf("webhooks request_timeout must be positive")
+	}
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("webhooks batch_size must be positive")
+	}
+	if c.MaxAttempts <= 0 {
+		return fmt.Errorf("webhooks max_attempts must be positive")
+	}
+	if c.InitialBackoff <= 0 || c.MaxBackoff < c.InitialBackoff {
+		return fmt.Errorf("webhooks backoff must satisfy 0 < initial_backoff <= max_backoff")
+	}
+	if c.DisableAfterFailures <= 0 {
+		return fmt.Errorf("webhooks disable_after_failures must be positive")
+	}
+	return nil
+}
@@ -6,10 +6,12 @@ import (
 	"net"
 	"net/url"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/2SSK/jwt/internal/config"
 	loggerConfig "github.com/2SSK/jwt/internal/logger"
+	"github.com/2SSK/jwt/internal/metrics"
 	pgxzero "github.com/jackc/pgx-zerolog"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -19,7 +21,10 @@ import (
 
 type Database struct {
 	Pool *pgxpool.Pool
-	log  *zerolog.Logger
+	// Replicas are read replica pools, see Replica
+	Replicas    []*pgxpool.Pool
+	nextReplica atomic.Uint32
+	log         *zerolog.Logger
 }
 
 // multiTracer allows chaining multiple tracers
@@ -52,7 +57,7 @@ func (mt *multiTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data p
 
 const DatabasePingTimeout = 10
 
-func New(cfg *config.Config, logger *zerolog.Logger) (*Database, error) {
+func New(cfg *config.Config, logger *zerolog.Logger, m *metrics.Metrics) (*Database, error) {
 	hostPort := net.JoinHostPort(cfg.Database.Host, strconv.Itoa(cfg.Database.Port))
 
 	// URL-encode the password
@@ -65,17 +70,74 @@ func New(cfg *config.Config, logger *zerolog.Logger) (*Database, error) {
 		cfg.Database.SSLMode,
 	)
 
+	if cfg.Database.MaxIdleConns != 0 {
+		logger.Warn().Msg("database.max_idle_conns is deprecated and ignored, use database.min_idle_conns for connections kept open while idle")
+	}
+
+	pool, err := newPool(cfg, logger, m, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	database := &Database{
+		Pool: pool,
+		log:  logger,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DatabasePingTimeout*time.Second)
+	defer cancel()
+	if err = pool.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	logger.Info().Msg("connected to the database")
+
+	// Replicas are optional: reads routed to one fall back to the primary, so an
+	// unreachable replica is only logged
+	for i, replicaDSN := range cfg.Database.ReplicaDSNs {
+		replica, err := newPool(cfg, logger, m, replicaDSN)
+		if err != nil {
+			pool.Close()
+			database.closeReplicas()
+			return nil, fmt.Errorf("replica %d: %w", i, err)
+		}
+		if err := replica.Ping(ctx); err != nil {
+			logger.Warn().Err(err).Int("replica", i).Msg("failed to ping database replica")
+		}
+		database.Replicas = append(database.Replicas, replica)
+	}
+	if len(database.Replicas) > 0 {
+		logger.Info().Int("replicas", len(database.Replicas)).Msg("routing reads to database replicas")
+	}
+
+	return database, nil
+}
+
+// newPool creates a pool with the configured limits
+func newPool(cfg *config.Config, logger *zerolog.Logger, m *metrics.Metrics, dsn string) (*pgxpool.Pool, error) {
 	pgxPoolConfig, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse pgx pool config: %w", err)
 	}
 
+	dbCfg := cfg.Database
+	pgxPoolConfig.MaxConns = int32(dbCfg.MaxOpenConns)
+	pgxPoolConfig.MinIdleConns = int32(dbCfg.MinIdleConns)
+	pgxPoolConfig.MaxConnLifetime = time.Duration(dbCfg.ConnMaxLifetime) * time.Second
+	pgxPoolConfig.MaxConnIdleTime = time.Duration(dbCfg.ConnMaxIdleTime) * time.Second
+
+	pgxPoolConfig.ConnConfig.Tracer = &queryTracer{
+		logger:    logger,
+		threshold: cfg.Observability.Logging.SlowQueryThreshold,
+		duration:  m.DBQueryDuration,
+	}
+
 	if cfg.Primary.Env == "local" {
 		globalLevel := logger.GetLevel()
 		pgxLogger := loggerConfig.NewPgxLogger(globalLevel)
-		// Chain tracers - New Relic first, then local logging
+		// Chain tracers - query timing first, then local logging
 		if pgxPoolConfig.ConnConfig.Tracer != nil {
-			// If New Relic tracer exists, create a multi-tracer
+			// If a tracer exists, create a multi-tracer
 			localTracer := &tracelog.TraceLog{
 				Logger:   pgxzero.NewLogger(pgxLogger),
 				LogLevel: tracelog.LogLevel(loggerConfig.GetPgxTraceLogLevel(globalLevel)),
@@ -96,24 +158,27 @@ func New(cfg *config.Config, logger *zerolog.Logger) (*Database, error) {
 		return nil, fmt.Errorf("failed to create pgx pool: %w", err)
 	}
 
-	database := &Database{
-		Pool: pool,
-		log:  logger,
-	}
+	return pool, nil
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), DatabasePingTimeout*time.Second)
-	defer cancel()
-	if err = pool.Ping(ctx); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+// Replica returns the next read replica, round robin, or nil when there are none.
+// Only reads that tolerate replication lag belong there.
+func (db *Database) Replica() *pgxpool.Pool {
+	if len(db.Replicas) == 0 {
+		return nil
 	}
+	return db.Replicas[int(db.nextReplica.Add(1)-1)%len(db.Replicas)]
+}
 
-	logger.Info().Msg("connected to the database")
-
-	return database, nil
+func (db *Database) closeReplicas() {
+	for _, replica := range db.Replicas {
+		replica.Close()
+	}
 }
 
 func (db *Database) Close() error {
 	db.log.Info().Msg("closing database connection pool")
 	db.Pool.Close()
+	db.closeReplicas()
 	return nil
 }
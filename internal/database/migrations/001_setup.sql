@@ -27,3 +27,8 @@ BEGIN
     RETURN NEW;
 END;
 $$ LANGUAGE plpgsql;
+
+---- create above / drop below ----
+
+DROP FUNCTION trigger_set_updated_at();
+DROP FUNCTION camel(anyelement);
@@ -1,4 +1,3 @@
--- +migrate Up
 CREATE TABLE users (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     first_name VARCHAR(100),
@@ -13,8 +12,6 @@ CREATE TABLE users (
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
-CREATE INDEX idx_users_email ON users(email);
-CREATE INDEX idx_users_phone ON users(phone);
+---- create above / drop below ----
 
--- +migrate Down
-DROP TABLE users;
\ No newline at end of file
+DROP TABLE users;
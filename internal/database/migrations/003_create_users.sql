@@ -1,16 +0,0 @@
-CREATE TABLE users (
-    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-    first_name VARCHAR(100),
-    last_name VARCHAR(100),
-    password VARCHAR(255),
-    email VARCHAR(255) UNIQUE,
-    phone VARCHAR(20),
-    token TEXT,
-    user_type VARCHAR(50),
-    refresh_token TEXT,
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
-);
-
-CREATE INDEX idx_users_email ON users(email);
-CREATE INDEX idx_users_phone ON users(phone);
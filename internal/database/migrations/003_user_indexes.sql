@@ -0,0 +1,7 @@
+CREATE INDEX idx_users_email ON users(email);
+CREATE INDEX idx_users_phone ON users(phone);
+
+---- create above / drop below ----
+
+DROP INDEX idx_users_phone;
+DROP INDEX idx_users_email;
@@ -0,0 +1,6 @@
+-- PHC strings grow with the configured salt and key lengths
+ALTER TABLE users ALTER COLUMN password TYPE TEXT;
+
+---- create above / drop below ----
+
+ALTER TABLE users ALTER COLUMN password TYPE VARCHAR(255);
@@ -0,0 +1,12 @@
+CREATE TABLE login_throttles (
+    scope TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (scope, subject)
+);
+
+---- create above / drop below ----
+
+DROP TABLE login_throttles;
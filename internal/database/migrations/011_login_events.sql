@@ -0,0 +1,20 @@
+CREATE TABLE login_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    email TEXT,
+    method TEXT NOT NULL,
+    success BOOLEAN NOT NULL,
+    failure_reason TEXT,
+    ip_address TEXT NOT NULL,
+    network TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    device_fingerprint TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_login_events_user_created ON login_events(user_id, created_at DESC);
+CREATE INDEX idx_login_events_user_success ON login_events(user_id) WHERE success;
+
+---- create above / drop below ----
+
+DROP TABLE login_events;
@@ -0,0 +1,20 @@
+ALTER TABLE sessions
+    ADD COLUMN client_id TEXT NOT NULL DEFAULT '',
+    ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN absolute_expires_at TIMESTAMP WITH TIME ZONE;
+
+-- Existing sessions get the default lifetimes
+UPDATE sessions SET
+    expires_at = last_seen_at + INTERVAL '7 days',
+    absolute_expires_at = created_at + INTERVAL '30 days';
+
+ALTER TABLE sessions
+    ALTER COLUMN expires_at SET NOT NULL,
+    ALTER COLUMN absolute_expires_at SET NOT NULL;
+
+---- create above / drop below ----
+
+ALTER TABLE sessions
+    DROP COLUMN absolute_expires_at,
+    DROP COLUMN expires_at,
+    DROP COLUMN client_id;
@@ -0,0 +1,19 @@
+ALTER TABLE sessions
+    ADD COLUMN auth_time TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN amr TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN acr SMALLINT NOT NULL DEFAULT 1;
+
+UPDATE sessions SET auth_time = created_at;
+
+ALTER TABLE sessions ALTER COLUMN auth_time SET NOT NULL;
+
+ALTER TABLE mfa_challenges ADD COLUMN first_factor TEXT NOT NULL DEFAULT 'password';
+
+---- create above / drop below ----
+
+ALTER TABLE mfa_challenges DROP COLUMN first_factor;
+
+ALTER TABLE sessions
+    DROP COLUMN acr,
+    DROP COLUMN amr,
+    DROP COLUMN auth_time;
@@ -0,0 +1,5 @@
+ALTER TABLE sessions ADD COLUMN dpop_jkt TEXT NOT NULL DEFAULT '';
+
+---- create above / drop below ----
+
+ALTER TABLE sessions DROP COLUMN dpop_jkt;
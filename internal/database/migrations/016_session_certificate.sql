@@ -0,0 +1,5 @@
+ALTER TABLE sessions ADD COLUMN cert_thumbprint TEXT NOT NULL DEFAULT '';
+
+---- create above / drop below ----
+
+ALTER TABLE sessions DROP COLUMN cert_thumbprint;
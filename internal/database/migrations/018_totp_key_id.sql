@@ -0,0 +1,7 @@
+-- The id of the mfa encryption key a secret is sealed with; empty for secrets
+-- sealed under auth.secret_key before the dedicated key existed
+ALTER TABLE user_totp ADD COLUMN key_id TEXT NOT NULL DEFAULT '';
+
+---- create above / drop below ----
+
+ALTER TABLE user_totp DROP COLUMN key_id;
@@ -19,7 +19,33 @@ import (
 //go:embed migrations/*.sql
 var migrations embed.FS
 
-func Migrate(ctx context.Context, logger *zerolog.Logger, cfg *config.Config) error {
+// migrationLockID is the advisory lock held while migrating, so replicas starting
+// together migrate one at a time
+const migrationLockID = int64(7308259816347152)
+
+const (
+	DirectionUp   = "up"
+	DirectionDown = "down"
+)
+
+// MigrationStep is one migration to apply or revert
+type MigrationStep struct {
+	Sequence  int32
+	Name      string
+	Direction string
+	SQL       string
+}
+
+// Migrator applies and reverts the embedded migrations over a connection of its own
+type Migrator struct {
+	conn   *pgx.Conn
+	tern   *tern.Migrator
+	logger *zerolog.Logger
+}
+
+// NewMigrator connects to the database and loads the embedded migrations. The
+// caller closes it.
+func NewMigrator(ctx context.Context, logger *zerolog.Logger, cfg *config.Config) (*Migrator, error) {
 	hostPort := net.JoinHostPort(cfg.Database.Host, strconv.Itoa(cfg.Database.Port))
 
 	// URL-encode the password
@@ -34,32 +60,174 @@ func Migrate(ctx context.Context, logger *zerolog.Logger, cfg *config.Config) er
 
 	conn, err := pgx.Connect(ctx, dsn)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer conn.Close(ctx)
 
 	m, err := tern.NewMigrator(ctx, conn, "schema_version")
 	if err != nil {
-		return fmt.Errorf("constructing database migrator: %w", err)
+		conn.Close(ctx)
+		return nil, fmt.Errorf("constructing database migrator: %w", err)
 	}
 	subtree, err := fs.Sub(migrations, "migrations")
 	if err != nil {
-		return fmt.Errorf("retrieving database migrations subtree: %w", err)
+		conn.Close(ctx)
+		return nil, fmt.Errorf("retrieving database migrations subtree: %w", err)
 	}
 	if err := m.LoadMigrations(subtree); err != nil {
-		return fmt.Errorf("loading database migrations: %w", err)
+		conn.Close(ctx)
+		return nil, fmt.Errorf("loading database migrations: %w", err)
+	}
+
+	m.OnStart = func(sequence int32, name, direction, _ string) {
+		logger.Info().Int32("version", sequence).Str("direction", direction).Msgf("running migration %s", name)
+	}
+
+	return &Migrator{conn: conn, tern: m, logger: logger}, nil
+}
+
+func (m *Migrator) Close(ctx context.Context) error {
+	return m.conn.Close(ctx)
+}
+
+// Migrations returns the migrations this binary knows of, in order
+func (m *Migrator) Migrations() []*tern.Migration {
+	return m.tern.Migrations
+}
+
+// Latest is the schema version this binary expects
+func (m *Migrator) Latest() int32 {
+	return int32(len(m.tern.Migrations))
+}
+
+// Version returns the schema version of the database
+func (m *Migrator) Version(ctx context.Context) (int32, error) {
+	version, err := m.tern.GetCurrentVersion(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("retrieving current database migration version: %w", err)
+	}
+	return version, nil
+}
+
+// Plan returns the migrations that would take the database from its current
+// version to target, in the order they would run
+func (m *Migrator) Plan(ctx context.Context, target int32) ([]MigrationStep, error) {
+	current, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.plan(current, target)
+}
+
+func (m *Migrator) plan(current, target int32) ([]MigrationStep, error) {
+	latest := m.Latest()
+	if current > latest {
+		return nil, fmt.Errorf("database schema version %d is newer than this binary's %d", current, latest)
+	}
+	if target < 0 || target > latest {
+		return nil, fmt.Errorf("target version %d is outside the valid versions of 0 to %d", target, latest)
+	}
+
+	var steps []MigrationStep
+	for v := current; v < target; v++ {
+		migration := m.tern.Migrations[v]
+		steps = append(steps, MigrationStep{Sequence: migration.Sequence, Name: migration.Name, Direction: DirectionUp, SQL: migration.UpSQL})
+	}
+	for v := current; v > target; v-- {
+		migration := m.tern.Migrations[v-1]
+		if migration.DownSQL == "" {
+			return nil, fmt.Errorf("migration %s cannot be reverted", migration.Name)
+		}
+		steps = append(steps, MigrationStep{Sequence: migration.Sequence, Name: migration.Name, Direction: DirectionDown, SQL: migration.DownSQL})
+	}
+
+	return steps, nil
+}
+
+// MigrateTo applies or reverts migrations until the database is at the target
+// version. It waits for any other instance migrating the same database to finish.
+func (m *Migrator) MigrateTo(ctx context.Context, target int32) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	from, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := m.plan(from, target); err != nil {
+		return err
+	}
+	if from == target {
+		m.logger.Info().Msgf("database schema up to date, version %d", from)
+		return nil
+	}
+
+	if err := m.tern.MigrateTo(ctx, target); err != nil {
+		return err
+	}
+	m.logger.Info().Msgf("migrated database schema, from %d to %d", from, target)
+	return nil
+}
+
+func (m *Migrator) lock(ctx context.Context) (func(), error) {
+	var acquired bool
+	if err := m.conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", migrationLockID).Scan(&acquired); err != nil {
+		return nil, fmt.Errorf("acquiring database migration lock: %w", err)
+	}
+	if !acquired {
+		m.logger.Info().Msg("waiting for another instance to finish migrating the database")
+		if _, err := m.conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+			return nil, fmt.Errorf("acquiring database migration lock: %w", err)
+		}
 	}
-	from, err := m.GetCurrentVersion(ctx)
+
+	return func() {
+		if _, err := m.conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID); err != nil {
+			m.logger.Error().Err(err).Msg("failed to release database migration lock")
+		}
+	}, nil
+}
+
+// Migrate applies all pending migrations. A database already migrated past this
+// binary, during a rollback or rolling deploy, is left alone.
+func Migrate(ctx context.Context, logger *zerolog.Logger, cfg *config.Config) error {
+	m, err := NewMigrator(ctx, logger, cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Close(ctx)
+
+	current, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if current > m.Latest() {
+		logger.Warn().Msgf("database schema version %d is newer than this binary's %d, not migrating", current, m.Latest())
+		return nil
+	}
+
+	return m.MigrateTo(ctx, m.Latest())
+}
+
+// CheckSchema makes sure the database has every migration this binary expects
+func CheckSchema(ctx context.Context, logger *zerolog.Logger, cfg *config.Config) error {
+	m, err := NewMigrator(ctx, logger, cfg)
 	if err != nil {
-		return fmt.Errorf("retreiving current database migration version")
+		return err
 	}
-	if err := m.Migrate(ctx); err != nil {
+	defer m.Close(ctx)
+
+	current, err := m.Version(ctx)
+	if err != nil {
 		return err
 	}
-	if from == int32(len(m.Migrations)) {
-		logger.Info().Msgf("database schema up to date, version %d", len(m.Migrations))
-	} else {
-		logger.Info().Msgf("migrated database schema, from %d to %d", from, len(m.Migrations))
+	switch {
+	case current < m.Latest():
+		return fmt.Errorf("database schema is at version %d but this binary needs %d, run \"jwt migrate up\"", current, m.Latest())
+	case current > m.Latest():
+		logger.Warn().Msgf("database schema version %d is newer than this binary's %d", current, m.Latest())
 	}
 	return nil
 }
@@ -0,0 +1,170 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/2SSK/jwt/internal/logger"
+	"github.com/2SSK/jwt/internal/tracing"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// queryTracer times every query, records it in a histogram by query name, wraps
+// it in a span and logs the ones slower than the threshold. A zero threshold turns
+// the logging off.
+type queryTracer struct {
+	logger    *zerolog.Logger
+	threshold time.Duration
+	duration  *prometheus.HistogramVec
+}
+
+type queryTraceKey struct{}
+
+type queryTrace struct {
+	start time.Time
+	sql   string
+	args  []any
+	span  trace.Span
+}
+
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	name := queryName(data.SQL)
+	operation, _, _ := strings.Cut(name, " ")
+	ctx, span := tracing.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "postgresql"),
+			attribute.String("db.operation.name", operation),
+			attribute.String("db.query.text", strings.Join(strings.Fields(data.SQL), " ")),
+		),
+	)
+	return context.WithValue(ctx, queryTraceKey{}, &queryTrace{start: time.Now(), sql: data.SQL, args: data.Args, span: span})
+}
+
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	query, ok := ctx.Value(queryTraceKey{}).(*queryTrace)
+	if !ok {
+		return
+	}
+
+	elapsed := time.Since(query.start)
+	name := queryName(query.sql)
+
+	status := "ok"
+	if data.Err != nil && data.Err != pgx.ErrNoRows {
+		status = "error"
+	}
+	t.duration.WithLabelValues(name, status).Observe(elapsed.Seconds())
+
+	if status == "error" {
+		query.span.RecordError(data.Err)
+		query.span.SetStatus(codes.Error, data.Err.Error())
+	}
+	query.span.End()
+
+	if t.threshold <= 0 || elapsed < t.threshold {
+		return
+	}
+
+	event := t.logger.Warn().
+		Str("query", name).
+		Dur("duration", elapsed).
+		Str("sql", strings.Join(strings.Fields(query.sql), " ")).
+		Strs("args", sanitizeArgs(query.args)).
+		Int64("rows", data.CommandTag.RowsAffected())
+	if requestID := logger.RequestIDFromContext(ctx); requestID != "" {
+		event = event.Str("request_id", requestID)
+	}
+	if data.Err != nil {
+		event = event.Err(data.Err)
+	}
+	event.Msg("slow database query")
+}
+
+// queryName names a query for metrics: the text of a leading "-- name: <name>"
+// comment, or else the statement and the table it works on, like "select users"
+func queryName(sql string) string {
+	sql = strings.TrimSpace(sql)
+	if rest, ok := strings.CutPrefix(sql, "-- name:"); ok {
+		name, _, _ := strings.Cut(rest, "\n")
+		return strings.TrimSpace(name)
+	}
+
+	fields := strings.Fields(strings.ToLower(sql))
+	if len(fields) == 0 {
+		return "unknown"
+	}
+
+	verb := fields[0]
+	var tableAfter string
+	switch verb {
+	case "select", "delete":
+		tableAfter = "from"
+	case "insert":
+		tableAfter = "into"
+	case "update":
+		tableAfter = "update"
+	default:
+		return verb
+	}
+
+	for i, field := range fields[:len(fields)-1] {
+		if field == tableAfter {
+			if table := tableName(fields[i+1]); table != "" {
+				return verb + " " + table
+			}
+			break
+		}
+	}
+	return verb
+}
+
+func tableName(field string) string {
+	name, _, _ := strings.Cut(field, "(")
+	return strings.Trim(name, `"`)
+}
+
+// sanitizeArgs renders query arguments for logging. Strings and bytes can hold
+// emails, password hashes or tokens, so only their length is shown.
+func sanitizeArgs(args []any) []string {
+	sanitized := make([]string, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case nil:
+			sanitized[i] = "NULL"
+		case string:
+			sanitized[i] = fmt.Sprintf("<string len=%d>", len(v))
+		case *string:
+			if v == nil {
+				sanitized[i] = "NULL"
+			} else {
+				sanitized[i] = fmt.Sprintf("<string len=%d>", len(*v))
+			}
+		case []byte:
+			sanitized[i] = fmt.Sprintf("<bytes len=%d>", len(v))
+		case []string:
+			sanitized[i] = fmt.Sprintf("<strings len=%d>", len(v))
+		case bool, int, int16, int32, int64, uint, uint16, uint32, uint64, float32, float64, uuid.UUID, time.Duration:
+			sanitized[i] = fmt.Sprint(v)
+		case *uuid.UUID:
+			if v == nil {
+				sanitized[i] = "NULL"
+			} else {
+				sanitized[i] = v.String()
+			}
+		case time.Time:
+			sanitized[i] = v.Format(time.RFC3339Nano)
+		default:
+			sanitized[i] = fmt.Sprintf("<%T>", v)
+		}
+	}
+	return sanitized
+}
@@ -30,6 +30,8 @@ type HTTPError struct {
 	Errors []FieldError `json:"errors"`
 	// action to be taken
 	Action *Action `json:"action"`
+	// seconds the client should wait before retrying, also sent as Retry-After
+	RetryAfter int `json:"retryAfter,omitempty"`
 }
 
 func (e *HTTPError) Error() string {
@@ -44,12 +46,13 @@ func (e *HTTPError) Is(target error) bool {
 
 func (e *HTTPError) WithMessage(message string) *HTTPError {
 	return &HTTPError{
-		Code:     e.Code,
-		Message:  message,
-		Status:   e.Status,
-		Override: e.Override,
-		Errors:   e.Errors,
-		Action:   e.Action,
+		Code:       e.Code,
+		Message:    message,
+		Status:     e.Status,
+		Override:   e.Override,
+		Errors:     e.Errors,
+		Action:     e.Action,
+		RetryAfter: e.RetryAfter,
 	}
 }
 
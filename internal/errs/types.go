@@ -2,6 +2,7 @@ package errs
 
 import (
 	"net/http"
+	"time"
 )
 
 func NewUnauthorizedError(message string, override bool) *HTTPError {
@@ -66,3 +67,59 @@ func NewInternalServerError() *HTTPError {
 func ValidationError(err error) *HTTPError {
 	return NewBadRequestError("Validation failed: "+err.Error(), false, nil, nil, nil)
 }
+
+func NewTooManyRequestsError(message string, override bool, retryAfter time.Duration) *HTTPError {
+	return &HTTPError{
+		Code:       MakeUpperCaseWithUnderscores(http.StatusText(http.StatusTooManyRequests)),
+		Message:    message,
+		Status:     http.StatusTooManyRequests,
+		Override:   override,
+		RetryAfter: retryAfterSeconds(retryAfter),
+	}
+}
+
+const CodeAccountLocked = "ACCOUNT_LOCKED"
+
+// NewAccountLockedError reports a temporary lockout after repeated failed logins
+func NewAccountLockedError(message string, retryAfter time.Duration) *HTTPError {
+	return &HTTPError{
+		Code:       CodeAccountLocked,
+		Message:    message,
+		Status:     http.StatusLocked,
+		Override:   true,
+		RetryAfter: retryAfterSeconds(retryAfter),
+	}
+}
+
+// retryAfterSeconds rounds up so clients never retry early
+func retryAfterSeconds(d time.Duration) int {
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+const CodeReauthenticationRequired = "REAUTHENTICATION_REQUIRED"
+
+// NewReauthenticationRequiredError asks the client to re-authenticate before retrying a sensitive request
+func NewReauthenticationRequiredError(message string) *HTTPError {
+	return &HTTPError{
+		Code:     CodeReauthenticationRequired,
+		Message:  message,
+		Status:   http.StatusUnauthorized,
+		Override: true,
+	}
+}
+
+const CodeInvalidDPoPProof = "INVALID_DPOP_PROOF"
+
+// NewInvalidDPoPProofError rejects a missing or invalid DPoP proof (RFC 9449)
+func NewInvalidDPoPProofError(message string) *HTTPError {
+	return &HTTPError{
+		Code:     CodeInvalidDPoPProof,
+		Message:  message,
+		Status:   http.StatusUnauthorized,
+		Override: true,
+	}
+}
@@ -1,8 +1,13 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
+	"github.com/2SSK/jwt/internal/errs"
+	utils "github.com/2SSK/jwt/internal/lib"
+	"github.com/2SSK/jwt/internal/middleware"
 	"github.com/2SSK/jwt/internal/model/user"
 	"github.com/2SSK/jwt/internal/service"
 	"github.com/2SSK/jwt/internal/validation"
@@ -10,12 +15,16 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+const maxDeviceNameLength = 100
+
 type AuthHandler struct {
-	userService *service.UserService
+	userService   *service.UserService
+	reauthService *service.ReauthService
+	tokenCookies  *TokenCookies
 }
 
-func NewAuthHandler(userService *service.UserService) *AuthHandler {
-	return &AuthHandler{userService: userService}
+func NewAuthHandler(userService *service.UserService, reauthService *service.ReauthService, tokenCookies *TokenCookies) *AuthHandler {
+	return &AuthHandler{userService: userService, reauthService: reauthService, tokenCookies: tokenCookies}
 }
 
 func (h *AuthHandler) SignUp(c echo.Context) error {
@@ -24,11 +33,17 @@ func (h *AuthHandler) SignUp(c echo.Context) error {
 		return err
 	}
 
-	response, err := h.userService.SignUp(c.Request().Context(), &payload)
+	response, err := h.userService.SignUp(c.Request().Context(), &payload, clientInfo(c))
 	if err != nil {
+		var httpErr *errs.HTTPError
+		if errors.As(err, &httpErr) {
+			return err
+		}
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
+	h.tokenCookies.Apply(c, &response.TokenResponse)
+
 	return c.JSON(http.StatusCreated, response)
 }
 
@@ -38,31 +53,128 @@ func (h *AuthHandler) Login(c echo.Context) error {
 		return err
 	}
 
-	response, err := h.userService.Login(c.Request().Context(), &payload)
+	response, challenge, err := h.userService.Login(c.Request().Context(), &payload, clientInfo(c))
 	if err != nil {
+		var httpErr *errs.HTTPError
+		if errors.As(err, &httpErr) {
+			return err
+		}
 		return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
 	}
+	if challenge != nil {
+		return c.JSON(http.StatusOK, challenge)
+	}
+
+	h.tokenCookies.Apply(c, &response.TokenResponse)
 
 	return c.JSON(http.StatusOK, response)
 }
 
 func (h *AuthHandler) RefreshToken(c echo.Context) error {
-	// Get user ID from context (set by RequireAuth middleware)
+	// Get user and session IDs from context (set by RequireRefreshToken middleware)
 	userID, ok := c.Get("user_id").(uuid.UUID)
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
 	}
+	sessionID, ok := c.Get("session_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
 
-	// Generate new tokens
-	accessToken, refreshToken, err := h.userService.GenerateTokens(c.Request().Context(), userID)
+	// Generate new tokens for the same session
+	response, err := h.userService.RefreshTokens(c.Request().Context(), userID, sessionID)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to generate tokens")
+		return err
 	}
 
-	response := user.TokenResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+	h.tokenCookies.Apply(c, response)
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// Logout revokes the current session and, for browser clients, clears the refresh
+// cookie
+func (h *AuthHandler) Logout(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+	sessionID, ok := c.Get("session_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+
+	if err := h.userService.Logout(c.Request().Context(), userID, sessionID); err != nil {
+		return err
 	}
 
+	h.tokenCookies.Clear(c)
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Reauthenticate upgrades the current session after the user proves their identity
+// again, returning tokens with a fresh auth_time
+func (h *AuthHandler) Reauthenticate(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+	sessionID, ok := c.Get("session_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+
+	var payload user.ReauthenticatePayload
+	if err := validation.BindAndValidate(c, &payload); err != nil {
+		return err
+	}
+
+	response, err := h.reauthService.Reauthenticate(c.Request().Context(), userID, sessionID, &payload, clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	h.tokenCookies.Apply(c, response)
+
 	return c.JSON(http.StatusOK, response)
 }
+
+// clientInfo describes the caller for login history and sessions. Apps can send a
+// stable X-Device-Id and a display name in X-Device-Name; otherwise browsers are
+// told apart by user agent and language. X-Client-Id selects per-client token
+// lifetimes.
+func clientInfo(c echo.Context) user.ClientInfo {
+	req := c.Request()
+
+	fingerprint := "ua:" + req.UserAgent() + "\n" + req.Header.Get("Accept-Language")
+	if deviceID := req.Header.Get("X-Device-Id"); deviceID != "" {
+		fingerprint = "device:" + deviceID
+	}
+
+	// Set by AuthMiddleware.DPoPProof when the request proved possession of a key
+	dpopJKT, _ := c.Get(middleware.DPoPJKTKey).(string)
+
+	var certThumbprint string
+	if cert := utils.ClientCertificate(req.TLS); cert != nil {
+		certThumbprint = utils.CertificateThumbprint(cert)
+	}
+
+	return user.ClientInfo{
+		IPAddress:         c.RealIP(),
+		UserAgent:         req.UserAgent(),
+		DeviceFingerprint: utils.HashToken(fingerprint),
+		DeviceName:        deviceName(req.Header.Get("X-Device-Name")),
+		ClientID:          strings.ToLower(strings.TrimSpace(req.Header.Get("X-Client-Id"))),
+		DPoPJKT:           dpopJKT,
+		CertThumbprint:    certThumbprint,
+	}
+}
+
+func deviceName(name string) string {
+	name = strings.TrimSpace(name)
+	if len(name) > maxDeviceNameLength {
+		name = strings.ToValidUTF8(name[:maxDeviceNameLength], "")
+	}
+	return name
+}
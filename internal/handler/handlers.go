@@ -6,19 +6,35 @@ import (
 )
 
 type Handlers struct {
-	Health  *HealthHandler
-	OpenAPI *OpenAPIHandler
-	Home    *HomeHandler
-	Auth    *AuthHandler
-	User    *UserHandler
+	Health       *HealthHandler
+	OpenAPI      *OpenAPIHandler
+	Home         *HomeHandler
+	Auth         *AuthHandler
+	User         *UserHandler
+	MFA          *MFAHandler
+	WebAuthn     *WebAuthnHandler
+	MagicLink    *MagicLinkHandler
+	LoginHistory *LoginHistoryHandler
+	Session      *SessionHandler
+	Webhook      *WebhookHandler
+	Token        *TokenHandler
 }
 
 func NewHandlers(s *server.Server, services *service.Services) *Handlers {
+	tokenCookies := NewTokenCookies(s)
+
 	return &Handlers{
-		Health:  NewHealthHandler(s),
-		OpenAPI: NewOpenAPIHandler(s),
-		Home:    NewHomeHandler(s),
-		Auth:    NewAuthHandler(services.User),
-		User:    NewUserHandler(services.User, services.AuthHelper),
+		Health:       NewHealthHandler(s),
+		OpenAPI:      NewOpenAPIHandler(s),
+		Home:         NewHomeHandler(s),
+		Auth:         NewAuthHandler(services.User, services.Reauth, tokenCookies),
+		User:         NewUserHandler(services.User, services.AuthHelper),
+		MFA:          NewMFAHandler(services.MFA, tokenCookies),
+		WebAuthn:     NewWebAuthnHandler(services.WebAuthn, tokenCookies),
+		MagicLink:    NewMagicLinkHandler(services.MagicLink, tokenCookies),
+		LoginHistory: NewLoginHistoryHandler(services.LoginHistory),
+		Session:      NewSessionHandler(services.Session),
+		Webhook:      NewWebhookHandler(services.Webhook),
+		Token:        NewTokenHandler(services.Token),
 	}
 }
@@ -59,6 +59,25 @@ func (h *HealthHandler) CheckHealth(c echo.Context) error {
 		logger.Info().Dur("response_time", time.Since(dbStart)).Msg("database health check passed")
 	}
 
+	// Replicas are reported but do not fail the check, their reads fall back to the primary
+	for i, replica := range h.server.DB.Replicas {
+		replicaStart := time.Now()
+		name := fmt.Sprintf("database_replica_%d", i)
+		if err := replica.Ping(ctx); err != nil {
+			checks[name] = map[string]interface{}{
+				"status":        "unhealthy",
+				"response_time": time.Since(replicaStart).String(),
+				"error":         err.Error(),
+			}
+			logger.Warn().Err(err).Int("replica", i).Msg("database replica health check failed")
+		} else {
+			checks[name] = map[string]interface{}{
+				"status":        "healthy",
+				"response_time": time.Since(replicaStart).String(),
+			}
+		}
+	}
+
 	// Set overall status
 	if !isHealthy {
 		response["status"] = "unhealthy"
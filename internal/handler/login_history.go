@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/2SSK/jwt/internal/service"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type LoginHistoryHandler struct {
+	loginHistoryService *service.LoginHistoryService
+}
+
+func NewLoginHistoryHandler(loginHistoryService *service.LoginHistoryService) *LoginHistoryHandler {
+	return &LoginHistoryHandler{loginHistoryService: loginHistoryService}
+}
+
+func (h *LoginHistoryHandler) GetMyLogins(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+
+	limit, offset := paginationParams(c)
+
+	response, err := h.loginHistoryService.GetLogins(c.Request().Context(), userID, limit, offset)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *LoginHistoryHandler) GetUserLogins(c echo.Context) error {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	limit, offset := paginationParams(c)
+
+	response, err := h.loginHistoryService.GetLogins(c.Request().Context(), userID, limit, offset)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/2SSK/jwt/internal/model/magiclink"
+	"github.com/2SSK/jwt/internal/service"
+	"github.com/2SSK/jwt/internal/validation"
+	"github.com/labstack/echo/v4"
+)
+
+type MagicLinkHandler struct {
+	magicLinkService *service.MagicLinkService
+	tokenCookies     *TokenCookies
+}
+
+func NewMagicLinkHandler(magicLinkService *service.MagicLinkService, tokenCookies *TokenCookies) *MagicLinkHandler {
+	return &MagicLinkHandler{magicLinkService: magicLinkService, tokenCookies: tokenCookies}
+}
+
+func (h *MagicLinkHandler) Request(c echo.Context) error {
+	var payload magiclink.RequestPayload
+	if err := validation.BindAndValidate(c, &payload); err != nil {
+		return err
+	}
+
+	response, err := h.magicLinkService.Request(c.Request().Context(), &payload)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusAccepted, response)
+}
+
+func (h *MagicLinkHandler) Verify(c echo.Context) error {
+	var payload magiclink.VerifyPayload
+	if err := validation.BindAndValidate(c, &payload); err != nil {
+		return err
+	}
+
+	response, challenge, err := h.magicLinkService.Verify(c.Request().Context(), &payload, clientInfo(c))
+	if err != nil {
+		return err
+	}
+	if challenge != nil {
+		return c.JSON(http.StatusOK, challenge)
+	}
+
+	h.tokenCookies.Apply(c, &response.TokenResponse)
+
+	return c.JSON(http.StatusOK, response)
+}
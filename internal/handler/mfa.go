@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/2SSK/jwt/internal/model/mfa"
+	"github.com/2SSK/jwt/internal/service"
+	"github.com/2SSK/jwt/internal/validation"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type MFAHandler struct {
+	mfaService   *service.MFAService
+	tokenCookies *TokenCookies
+}
+
+func NewMFAHandler(mfaService *service.MFAService, tokenCookies *TokenCookies) *MFAHandler {
+	return &MFAHandler{mfaService: mfaService, tokenCookies: tokenCookies}
+}
+
+func (h *MFAHandler) Status(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+
+	response, err := h.mfaService.Status(c.Request().Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *MFAHandler) EnrollTOTP(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+
+	response, err := h.mfaService.EnrollTOTP(c.Request().Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *MFAHandler) ConfirmTOTP(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+
+	var payload mfa.CodePayload
+	if err := validation.BindAndValidate(c, &payload); err != nil {
+		return err
+	}
+
+	response, err := h.mfaService.ConfirmTOTP(c.Request().Context(), userID, payload.Code)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *MFAHandler) DisableTOTP(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+
+	var payload mfa.DisableTOTPPayload
+	if err := validation.BindAndValidate(c, &payload); err != nil {
+		return err
+	}
+
+	if err := h.mfaService.DisableTOTP(c.Request().Context(), userID, &payload); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *MFAHandler) RegenerateRecoveryCodes(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+
+	var payload mfa.CodePayload
+	if err := validation.BindAndValidate(c, &payload); err != nil {
+		return err
+	}
+
+	response, err := h.mfaService.RegenerateRecoveryCodes(c.Request().Context(), userID, payload.Code)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *MFAHandler) Verify(c echo.Context) error {
+	var payload mfa.VerifyPayload
+	if err := validation.BindAndValidate(c, &payload); err != nil {
+		return err
+	}
+
+	response, err := h.mfaService.Verify(c.Request().Context(), &payload, clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	h.tokenCookies.Apply(c, &response.TokenResponse)
+
+	return c.JSON(http.StatusOK, response)
+}
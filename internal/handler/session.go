@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/2SSK/jwt/internal/service"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type SessionHandler struct {
+	sessionService *service.SessionService
+}
+
+func NewSessionHandler(sessionService *service.SessionService) *SessionHandler {
+	return &SessionHandler{sessionService: sessionService}
+}
+
+func (h *SessionHandler) GetMySessions(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+	sessionID, ok := c.Get("session_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+
+	response, err := h.sessionService.GetSessions(c.Request().Context(), userID, &sessionID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *SessionHandler) RevokeMySession(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid session ID")
+	}
+
+	if err := h.sessionService.Revoke(c.Request().Context(), userID, sessionID); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RevokeMyOtherSessions signs the user out everywhere except the current session
+func (h *SessionHandler) RevokeMyOtherSessions(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+	sessionID, ok := c.Get("session_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+
+	response, err := h.sessionService.RevokeAll(c.Request().Context(), userID, &sessionID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *SessionHandler) GetUserSessions(c echo.Context) error {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	response, err := h.sessionService.GetSessions(c.Request().Context(), userID, nil)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *SessionHandler) RevokeUserSession(c echo.Context) error {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid session ID")
+	}
+
+	if err := h.sessionService.Revoke(c.Request().Context(), userID, sessionID); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *SessionHandler) RevokeUserSessions(c echo.Context) error {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	response, err := h.sessionService.RevokeAll(c.Request().Context(), userID, nil)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
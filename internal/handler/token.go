@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/2SSK/jwt/internal/model/token"
+	"github.com/2SSK/jwt/internal/service"
+	"github.com/2SSK/jwt/internal/validation"
+	"github.com/labstack/echo/v4"
+)
+
+type TokenHandler struct {
+	tokenService *service.TokenService
+}
+
+func NewTokenHandler(tokenService *service.TokenService) *TokenHandler {
+	return &TokenHandler{tokenService: tokenService}
+}
+
+func (h *TokenHandler) Introspect(c echo.Context) error {
+	var payload token.IntrospectPayload
+	if err := validation.BindAndValidate(c, &payload); err != nil {
+		return err
+	}
+
+	response, err := h.tokenService.Introspect(c.Request().Context(), &payload)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// JWKS publishes the public token signing keys. Verifiers cache them for a few
+// minutes, so a new key is picked up shortly after it is added.
+func (h *TokenHandler) JWKS(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, h.tokenService.JWKS())
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/2SSK/jwt/internal/config"
+	utils "github.com/2SSK/jwt/internal/lib"
+	"github.com/2SSK/jwt/internal/middleware"
+	"github.com/2SSK/jwt/internal/model/user"
+	"github.com/2SSK/jwt/internal/server"
+	"github.com/labstack/echo/v4"
+)
+
+// TokenModeHeader lets browser clients ask for the refresh token as a cookie
+const TokenModeHeader = "X-Token-Mode"
+
+// TokenCookies delivers refresh tokens to browser clients. They opt in with
+// "X-Token-Mode: cookie" when logging in; refreshes that were authenticated by
+// the cookie keep using it.
+type TokenCookies struct {
+	config *config.RefreshCookieConfig
+	secret string
+}
+
+func NewTokenCookies(s *server.Server) *TokenCookies {
+	return &TokenCookies{config: s.Config.RefreshCookie, secret: s.Config.Auth.SecretKey}
+}
+
+// Apply moves the refresh token out of the response body into an HttpOnly cookie,
+// replacing it with the CSRF token the client must send back when refreshing.
+// Responses for other clients are left untouched.
+func (t *TokenCookies) Apply(c echo.Context, tokens *user.TokenResponse) {
+	if !t.browserMode(c) {
+		return
+	}
+
+	csrfToken := utils.CSRFToken(t.secret, tokens.RefreshToken)
+	c.SetCookie(utils.RefreshTokenCookie(t.config, tokens.RefreshToken, tokens.RefreshTokenExpiresAt))
+	c.SetCookie(utils.CSRFCookie(t.config, csrfToken, tokens.RefreshTokenExpiresAt))
+
+	tokens.RefreshToken = ""
+	tokens.CSRFToken = csrfToken
+}
+
+// Clear removes the refresh and CSRF cookies
+func (t *TokenCookies) Clear(c echo.Context) {
+	for _, cookie := range []*http.Cookie{
+		utils.RefreshTokenCookie(t.config, "", time.Unix(0, 0)),
+		utils.CSRFCookie(t.config, "", time.Unix(0, 0)),
+	} {
+		cookie.MaxAge = -1
+		c.SetCookie(cookie)
+	}
+}
+
+func (t *TokenCookies) browserMode(c echo.Context) bool {
+	if fromCookie, _ := c.Get(middleware.RefreshCookieKey).(bool); fromCookie {
+		return true
+	}
+	return strings.EqualFold(c.Request().Header.Get(TokenModeHeader), "cookie")
+}
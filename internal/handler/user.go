@@ -106,3 +106,57 @@ func (h *UserHandler) DeleteUser(c echo.Context) error {
 
 	return c.NoContent(http.StatusNoContent)
 }
+
+func (h *UserHandler) ChangePassword(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+
+	sessionID, ok := c.Get("session_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+
+	var payload user.ChangePasswordPayload
+	if err := validation.BindAndValidate(c, &payload); err != nil {
+		return err
+	}
+
+	if err := h.userService.ChangePassword(c.Request().Context(), userID, sessionID, &payload); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *UserHandler) ResetPassword(c echo.Context) error {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	var payload user.ResetPasswordPayload
+	if err := validation.BindAndValidate(c, &payload); err != nil {
+		return err
+	}
+
+	if err := h.userService.ResetPassword(c.Request().Context(), userID, &payload); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *UserHandler) UnlockUser(c echo.Context) error {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	if err := h.userService.UnlockUser(c.Request().Context(), userID); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
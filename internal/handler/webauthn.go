@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/2SSK/jwt/internal/model/webauthn"
+	"github.com/2SSK/jwt/internal/service"
+	"github.com/2SSK/jwt/internal/validation"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type WebAuthnHandler struct {
+	webauthnService *service.WebAuthnService
+	tokenCookies    *TokenCookies
+}
+
+func NewWebAuthnHandler(webauthnService *service.WebAuthnService, tokenCookies *TokenCookies) *WebAuthnHandler {
+	return &WebAuthnHandler{webauthnService: webauthnService, tokenCookies: tokenCookies}
+}
+
+func (h *WebAuthnHandler) BeginRegistration(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+
+	response, err := h.webauthnService.BeginRegistration(c.Request().Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *WebAuthnHandler) FinishRegistration(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+
+	var payload webauthn.FinishRegistrationPayload
+	if err := validation.BindAndValidate(c, &payload); err != nil {
+		return err
+	}
+
+	response, err := h.webauthnService.FinishRegistration(c.Request().Context(), userID, &payload)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, response)
+}
+
+func (h *WebAuthnHandler) BeginLogin(c echo.Context) error {
+	response, err := h.webauthnService.BeginLogin(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *WebAuthnHandler) FinishLogin(c echo.Context) error {
+	var payload webauthn.FinishLoginPayload
+	if err := validation.BindAndValidate(c, &payload); err != nil {
+		return err
+	}
+
+	response, err := h.webauthnService.FinishLogin(c.Request().Context(), &payload, clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	h.tokenCookies.Apply(c, &response.TokenResponse)
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *WebAuthnHandler) BeginMFA(c echo.Context) error {
+	var payload webauthn.BeginMFAPayload
+	if err := validation.BindAndValidate(c, &payload); err != nil {
+		return err
+	}
+
+	response, err := h.webauthnService.BeginMFA(c.Request().Context(), &payload)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *WebAuthnHandler) FinishMFA(c echo.Context) error {
+	var payload webauthn.FinishMFAPayload
+	if err := validation.BindAndValidate(c, &payload); err != nil {
+		return err
+	}
+
+	response, err := h.webauthnService.FinishMFA(c.Request().Context(), &payload, clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	h.tokenCookies.Apply(c, &response.TokenResponse)
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *WebAuthnHandler) BeginReauthentication(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+
+	response, err := h.webauthnService.BeginReauthentication(c.Request().Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *WebAuthnHandler) FinishReauthentication(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+	sessionID, ok := c.Get("session_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+
+	var payload webauthn.FinishLoginPayload
+	if err := validation.BindAndValidate(c, &payload); err != nil {
+		return err
+	}
+
+	response, err := h.webauthnService.FinishReauthentication(c.Request().Context(), userID, sessionID, &payload)
+	if err != nil {
+		return err
+	}
+
+	h.tokenCookies.Apply(c, response)
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *WebAuthnHandler) GetCredentials(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+
+	response, err := h.webauthnService.GetCredentials(c.Request().Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *WebAuthnHandler) RenameCredential(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+
+	credentialID, err := uuid.Parse(c.Param("credential_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid credential ID")
+	}
+
+	var payload webauthn.RenameCredentialPayload
+	if err := validation.BindAndValidate(c, &payload); err != nil {
+		return err
+	}
+
+	response, err := h.webauthnService.RenameCredential(c.Request().Context(), userID, credentialID, payload.Name)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *WebAuthnHandler) DeleteCredential(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication")
+	}
+
+	credentialID, err := uuid.Parse(c.Param("credential_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid credential ID")
+	}
+
+	if err := h.webauthnService.DeleteCredential(c.Request().Context(), userID, credentialID); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
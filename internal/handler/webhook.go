@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/2SSK/jwt/internal/model/webhook"
+	"github.com/2SSK/jwt/internal/service"
+	"github.com/2SSK/jwt/internal/validation"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+}
+
+func NewWebhookHandler(webhookService *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+func (h *WebhookHandler) CreateEndpoint(c echo.Context) error {
+	var payload webhook.CreateEndpointPayload
+	if err := validation.BindAndValidate(c, &payload); err != nil {
+		return err
+	}
+
+	var createdBy *uuid.UUID
+	if userID, ok := c.Get("user_id").(uuid.UUID); ok {
+		createdBy = &userID
+	}
+
+	response, err := h.webhookService.CreateEndpoint(c.Request().Context(), createdBy, &payload)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, response)
+}
+
+func (h *WebhookHandler) GetEndpoints(c echo.Context) error {
+	limit, offset := paginationParams(c)
+
+	endpoints, err := h.webhookService.GetEndpoints(c.Request().Context(), limit, offset)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, endpoints)
+}
+
+func (h *WebhookHandler) GetEndpoint(c echo.Context) error {
+	endpointID, err := uuid.Parse(c.Param("webhook_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid webhook ID")
+	}
+
+	endpoint, err := h.webhookService.GetEndpoint(c.Request().Context(), endpointID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, endpoint)
+}
+
+func (h *WebhookHandler) UpdateEndpoint(c echo.Context) error {
+	endpointID, err := uuid.Parse(c.Param("webhook_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid webhook ID")
+	}
+
+	var payload webhook.UpdateEndpointPayload
+	if err := validation.BindAndValidate(c, &payload); err != nil {
+		return err
+	}
+
+	endpoint, err := h.webhookService.UpdateEndpoint(c.Request().Context(), endpointID, &payload)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, endpoint)
+}
+
+func (h *WebhookHandler) DeleteEndpoint(c echo.Context) error {
+	endpointID, err := uuid.Parse(c.Param("webhook_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid webhook ID")
+	}
+
+	if err := h.webhookService.DeleteEndpoint(c.Request().Context(), endpointID); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) GetDeliveries(c echo.Context) error {
+	endpointID, err := uuid.Parse(c.Param("webhook_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid webhook ID")
+	}
+
+	limit, offset := paginationParams(c)
+
+	deliveries, err := h.webhookService.GetDeliveries(c.Request().Context(), endpointID, limit, offset)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, deliveries)
+}
+
+func (h *WebhookHandler) Redeliver(c echo.Context) error {
+	endpointID, err := uuid.Parse(c.Param("webhook_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid webhook ID")
+	}
+
+	deliveryID, err := uuid.Parse(c.Param("delivery_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid delivery ID")
+	}
+
+	delivery, err := h.webhookService.Redeliver(c.Request().Context(), endpointID, deliveryID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusAccepted, delivery)
+}
+
+// paginationParams reads limit/offset query params, falling back to 10/0
+func paginationParams(c echo.Context) (limit, offset int) {
+	limit = 10
+
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	if o, err := strconv.Atoi(c.QueryParam("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	return limit, offset
+}
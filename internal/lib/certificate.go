@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"net"
+	"net/url"
+	"slices"
+
+	"github.com/2SSK/jwt/internal/config"
+)
+
+// ClientCertificate returns the verified client certificate of a TLS connection,
+// or nil when there is none
+func ClientCertificate(state *tls.ConnectionState) *x509.Certificate {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	return state.PeerCertificates[0]
+}
+
+// CertificateThumbprint is the base64url SHA-256 hash of the DER certificate, as
+// carried in the cnf.x5t#S256 claim (RFC 8705 section 3.1)
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// MatchesTLSClientAuth reports whether a client certificate is the one registered
+// for a tls_client_auth client
+func MatchesTLSClientAuth(cert *x509.Certificate, auth config.TLSClientAuth) bool {
+	switch {
+	case auth.SubjectDN != "":
+		return cert.Subject.String() == auth.SubjectDN
+	case auth.SANDNS != "":
+		return slices.Contains(cert.DNSNames, auth.SANDNS)
+	case auth.SANURI != "":
+		return slices.ContainsFunc(cert.URIs, func(u *url.URL) bool { return u.String() == auth.SANURI })
+	case auth.SANIP != "":
+		ip := net.ParseIP(auth.SANIP)
+		return ip != nil && slices.ContainsFunc(cert.IPAddresses, ip.Equal)
+	case auth.SANEmail != "":
+		return slices.Contains(cert.EmailAddresses, auth.SANEmail)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/2SSK/jwt/internal/config"
+)
+
+// RefreshTokenCookie holds a browser client's refresh token. It is HttpOnly and
+// only sent to the refresh endpoint.
+func RefreshTokenCookie(cfg *config.RefreshCookieConfig, token string, expiresAt time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     cfg.Name,
+		Value:    token,
+		Path:     cfg.Path,
+		Domain:   cfg.Domain,
+		Expires:  expiresAt,
+		Secure:   cfg.Secure,
+		HttpOnly: true,
+		SameSite: cfg.SameSiteMode(),
+	}
+}
+
+// CSRFCookie holds the CSRF token for the current refresh cookie. Scripts must be
+// able to read it to echo it in the CSRF header, so it is not HttpOnly.
+func CSRFCookie(cfg *config.RefreshCookieConfig, token string, expiresAt time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     cfg.CSRFCookieName,
+		Value:    token,
+		Path:     "/",
+		Domain:   cfg.Domain,
+		Expires:  expiresAt,
+		Secure:   cfg.Secure,
+		SameSite: cfg.SameSiteMode(),
+	}
+}
+
+// CSRFToken derives the CSRF token for a refresh token. Binding it to the token
+// means a cookie planted by a sibling subdomain cannot be paired with a forged
+// header, and it rotates along with the refresh token.
+func CSRFToken(secret, refreshToken string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("csrf:" + refreshToken))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ValidCSRFToken checks a submitted CSRF token against the refresh token
+func ValidCSRFToken(secret, refreshToken, csrfToken string) bool {
+	return hmac.Equal([]byte(CSRFToken(secret, refreshToken)), []byte(csrfToken))
+}
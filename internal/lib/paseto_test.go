@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// Test vectors from https://github.com/paseto-standard/test-vectors (v4.json)
+
+const (
+	pasetoVectorLocalKey  = "707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f"
+	pasetoVectorSecretKey = "b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a37741eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2"
+
+	pasetoVectorSigned = `{"data":"this is a signed message","exp":"2022-01-01T00:00:00+00:00"}`
+	pasetoVectorSecret = `{"data":"this is a secret message","exp":"2022-01-01T00:00:00+00:00"}`
+	pasetoVectorHidden = `{"data":"this is a hidden message","exp":"2022-01-01T00:00:00+00:00"}`
+	pasetoVectorFooter = "eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9"
+)
+
+var pasetoLocalVectors = []struct {
+	name    string
+	nonce   string
+	payload string
+	token   string
+}{
+	{
+		name:    "4-E-1",
+		nonce:   "0000000000000000000000000000000000000000000000000000000000000000",
+		payload: pasetoVectorSecret,
+		token:   "v4.local.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQAr68PS4AXe7If_ZgesdkUMvSwscFlAl1pk5HC0e8kApeaqMfGo_7OpBnwJOAbY9V7WU6abu74MmcUE8YWAiaArVI8XJ5hOb_4v9RmDkneN0S92dx0OW4pgy7omxgf3S8c3LlQg",
+	},
+	{
+		name:    "4-E-2",
+		nonce:   "0000000000000000000000000000000000000000000000000000000000000000",
+		payload: pasetoVectorHidden,
+		token:   "v4.local.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQAr68PS4AXe7If_ZgesdkUMvS2csCgglvpk5HC0e8kApeaqMfGo_7OpBnwJOAbY9V7WU6abu74MmcUE8YWAiaArVI8XIemu9chy3WVKvRBfg6t8wwYHK0ArLxxfZP73W_vfwt5A",
+	},
+	{
+		name:    "4-E-3",
+		nonce:   "df654812bac492663825520ba2f6e67cf5ca5bdc13d4e7507a98cc4c2fcc3ad8",
+		payload: pasetoVectorSecret,
+		token:   "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WkwMsYXw6FSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t6-tyebyWG6Ov7kKvBdkrrAJ837lKP3iDag2hzUPHuMKA",
+	},
+	{
+		name:    "4-E-4",
+		nonce:   "df654812bac492663825520ba2f6e67cf5ca5bdc13d4e7507a98cc4c2fcc3ad8",
+		payload: pasetoVectorHidden,
+		token:   "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WiA8rd3wgFSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t4gt6TiLm55vIH8c_lGxxZpE3AWlH4WTR0v45nsWoU3gQ",
+	},
+}
+
+// Tokens with a footer, which the service never issues but must authenticate
+var pasetoLocalFooterVectors = []struct {
+	name    string
+	payload string
+	token   string
+}{
+	{
+		name:    "4-E-5",
+		payload: pasetoVectorSecret,
+		token:   "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WkwMsYXw6FSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t4x-RMNXtQNbz7FvFZ_G-lFpk5RG3EOrwDL6CgDqcerSQ." + pasetoVectorFooter,
+	},
+	{
+		name:    "4-E-6",
+		payload: pasetoVectorHidden,
+		token:   "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WiA8rd3wgFSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t6pWSA5HX2wjb3P-xLQg5K5feUCX4P2fpVK3ZLWFbMSxQ." + pasetoVectorFooter,
+	},
+}
+
+const (
+	pasetoVectorPublic1 = "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9bg_XBBzds8lTZShVlwwKSgeKpLT3yukTw6JUz3W4h_ExsQV-P0V54zemZDcAxFaSeef1QlXEFtkqxT1ciiQEDA"
+	pasetoVectorPublic2 = "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9v3Jt8mx_TdM2ceTGoqwrh4yDFn0XsHvvV_D0DtwQxVrJEBMl0F2caAdgnpKlt4p7xBnx1HcO-SPo8FPp214HDw." + pasetoVectorFooter
+	// 4-S-3 is signed with the implicit assertion {"test-vector":"4-S-3"}
+	pasetoVectorPublic3 = "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9NPWciuD3d0o5eXJXG5pJy-DiVEoyPYWs1YSTwWHNJq6DZD3je5gf-0M4JR9ipdUSJbIovzmBECeaWmaqcaP0DQ." + pasetoVectorFooter
+)
+
+func newVectorPasetoLocal(t *testing.T) *PasetoV4Local {
+	t.Helper()
+	return &PasetoV4Local{key: mustDecodeHex(t, pasetoVectorLocalKey)}
+}
+
+func newVectorPasetoPublic(t *testing.T) *PasetoV4Public {
+	t.Helper()
+	return &PasetoV4Public{key: ed25519.PrivateKey(mustDecodeHex(t, pasetoVectorSecretKey))}
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestPasetoV4LocalVectors(t *testing.T) {
+	p := newVectorPasetoLocal(t)
+
+	for _, v := range pasetoLocalVectors {
+		t.Run(v.name, func(t *testing.T) {
+			token, err := p.encrypt([]byte(v.payload), mustDecodeHex(t, v.nonce))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token != v.token {
+				t.Errorf("encrypt = %s, want %s", token, v.token)
+			}
+
+			payload, err := p.Decrypt(v.token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(payload) != v.payload {
+				t.Errorf("decrypt = %s, want %s", payload, v.payload)
+			}
+		})
+	}
+
+	for _, v := range pasetoLocalFooterVectors {
+		t.Run(v.name, func(t *testing.T) {
+			payload, err := p.Decrypt(v.token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(payload) != v.payload {
+				t.Errorf("decrypt = %s, want %s", payload, v.payload)
+			}
+		})
+	}
+}
+
+func TestPasetoV4PublicVectors(t *testing.T) {
+	p := newVectorPasetoPublic(t)
+
+	if token := p.Sign([]byte(pasetoVectorSigned)); token != pasetoVectorPublic1 {
+		t.Errorf("4-S-1: sign = %s, want %s", token, pasetoVectorPublic1)
+	}
+
+	for name, token := range map[string]string{"4-S-1": pasetoVectorPublic1, "4-S-2": pasetoVectorPublic2} {
+		payload, err := p.Verify(token)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if string(payload) != pasetoVectorSigned {
+			t.Errorf("%s: verify = %s, want %s", name, payload, pasetoVectorSigned)
+		}
+	}
+}
+
+// TestPasetoV4Failures covers the cases of the spec's failure vectors: tokens of
+// the other purpose or version, and tokens whose body, footer or implicit
+// assertion do not match what was authenticated
+func TestPasetoV4Failures(t *testing.T) {
+	local := newVectorPasetoLocal(t)
+	public := newVectorPasetoPublic(t)
+
+	localToken := pasetoLocalVectors[0].token
+	localBody := strings.TrimPrefix(localToken, PasetoV4LocalHeader)
+	publicBody := strings.TrimPrefix(pasetoVectorPublic1, PasetoV4PublicHeader)
+
+	localCases := map[string]string{
+		"public token":          pasetoVectorPublic1,
+		"v3 header":             "v3.local." + localBody,
+		"no header":             localBody,
+		"flipped ciphertext":    PasetoV4LocalHeader + flipBase64(localBody, 50),
+		"flipped tag":           PasetoV4LocalHeader + flipBase64(localBody, len(localBody)-2),
+		"added footer":          localToken + "." + pasetoVectorFooter,
+		"removed footer":        strings.TrimSuffix(pasetoLocalFooterVectors[0].token, "."+pasetoVectorFooter),
+		"changed footer":        pasetoLocalFooterVectors[0].token[:len(pasetoLocalFooterVectors[0].token)-2] + "fQ",
+		"truncated":             localToken[:len(PasetoV4LocalHeader)+80],
+		"padded base64":         localToken + "=",
+		"invalid base64":        PasetoV4LocalHeader + "!" + localBody[1:],
+		"empty":                 "",
+		"header only":           PasetoV4LocalHeader,
+		"nonce and tag only":    PasetoV4LocalHeader + localBody[:43] + localBody[len(localBody)-43:],
+		"trailing garbage body": localToken + "AA",
+	}
+	for name, token := range localCases {
+		if _, err := local.Decrypt(token); err == nil {
+			t.Errorf("v4.local %s: decrypt succeeded", name)
+		}
+	}
+
+	otherKey := &PasetoV4Local{key: make([]byte, 32)}
+	if _, err := otherKey.Decrypt(localToken); err == nil {
+		t.Error("v4.local wrong key: decrypt succeeded")
+	}
+
+	publicCases := map[string]string{
+		"local token":          localToken,
+		"v3 header":            "v3.public." + publicBody,
+		"flipped payload":      PasetoV4PublicHeader + flipBase64(publicBody, 10),
+		"flipped signature":    PasetoV4PublicHeader + flipBase64(publicBody, len(publicBody)-2),
+		"added footer":         pasetoVectorPublic1 + "." + pasetoVectorFooter,
+		"removed footer":       strings.TrimSuffix(pasetoVectorPublic2, "."+pasetoVectorFooter),
+		"implicit assertion":   pasetoVectorPublic3,
+		"truncated":            pasetoVectorPublic1[:len(PasetoV4PublicHeader)+40],
+		"padded base64":        pasetoVectorPublic1 + "=",
+		"empty":                "",
+		"header only":          PasetoV4PublicHeader,
+		"trailing garbage sig": pasetoVectorPublic1 + "AA",
+	}
+	for name, token := range publicCases {
+		if _, err := public.Verify(token); err == nil {
+			t.Errorf("v4.public %s: verify succeeded", name)
+		}
+	}
+}
+
+func TestPasetoV4RoundTrip(t *testing.T) {
+	local := newVectorPasetoLocal(t)
+	public := newVectorPasetoPublic(t)
+	payload := []byte(`{"user_id":"123","typ":"access"}`)
+
+	first, err := local.Encrypt(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := local.Encrypt(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Error("v4.local tokens for the same payload are equal, nonce is not random")
+	}
+	if decrypted, err := local.Decrypt(first); err != nil || string(decrypted) != string(payload) {
+		t.Errorf("v4.local round trip = %s, %v", decrypted, err)
+	}
+
+	if verified, err := public.Verify(public.Sign(payload)); err != nil || string(verified) != string(payload) {
+		t.Errorf("v4.public round trip = %s, %v", verified, err)
+	}
+}
+
+// flipBase64 changes the character at i of an unpadded base64url string
+func flipBase64(s string, i int) string {
+	c := byte('A')
+	if s[i] == 'A' {
+		c = 'B'
+	}
+	return s[:i] + string(c) + s[i+1:]
+}
@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/2SSK/jwt/internal/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrUnknownPasswordHash = errors.New("unrecognized password hash format")
+
+// PasswordHasher produces and checks PHC formatted password hashes, e.g.
+// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>. Bcrypt's $2a$/$2b$/$2y$ strings
+// are accepted too, so hashes created before the switch to Argon2id keep working.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(encoded, password string) (bool, error)
+	// NeedsRehash reports whether encoded was made with a different algorithm or
+	// weaker parameters than the configured ones
+	NeedsRehash(encoded string) bool
+}
+
+type passwordHasher struct {
+	cfg *config.PasswordHashConfig
+}
+
+func NewPasswordHasher(cfg *config.PasswordHashConfig) PasswordHasher {
+	return &passwordHasher{cfg: cfg}
+}
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (h *passwordHasher) Hash(password string) (string, error) {
+	if h.cfg.Algorithm == config.PasswordAlgorithmBcrypt {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.cfg.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashed), nil
+	}
+
+	salt := make([]byte, h.cfg.Argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.cfg.Argon2Iterations, h.cfg.Argon2Memory, h.cfg.Argon2Parallelism, h.cfg.Argon2KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.cfg.Argon2Memory, h.cfg.Argon2Iterations, h.cfg.Argon2Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *passwordHasher) Verify(encoded, password string) (bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		params, err := parseArgon2id(encoded)
+		if err != nil {
+			return false, err
+		}
+		key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+		return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+
+	case isBcryptHash(encoded):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) || errors.Is(err, bcrypt.ErrPasswordTooLong) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	return false, ErrUnknownPasswordHash
+}
+
+func (h *passwordHasher) NeedsRehash(encoded string) bool {
+	switch h.cfg.Algorithm {
+	case config.PasswordAlgorithmArgon2id:
+		params, err := parseArgon2id(encoded)
+		if err != nil {
+			return true
+		}
+		return params.memory < h.cfg.Argon2Memory ||
+			params.iterations < h.cfg.Argon2Iterations ||
+			params.parallelism != h.cfg.Argon2Parallelism ||
+			uint32(len(params.salt)) < h.cfg.Argon2SaltLength ||
+			uint32(len(params.key)) < h.cfg.Argon2KeyLength
+
+	case config.PasswordAlgorithmBcrypt:
+		if !isBcryptHash(encoded) {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return err != nil || cost < h.cfg.BcryptCost
+	}
+
+	return false
+}
+
+func isBcryptHash(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func parseArgon2id(encoded string) (*argon2Params, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, ErrUnknownPasswordHash
+	}
+	if version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	params := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return nil, ErrUnknownPasswordHash
+	}
+	if params.iterations == 0 || params.parallelism == 0 {
+		return nil, ErrUnknownPasswordHash
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrUnknownPasswordHash
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(params.key) == 0 {
+		return nil, ErrUnknownPasswordHash
+	}
+
+	return params, nil
+}
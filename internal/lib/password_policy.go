@@ -0,0 +1,218 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/2SSK/jwt/internal/config"
+)
+
+// PasswordPolicy checks new passwords against the configured rules and the
+// breached password list. It does not know about password history, which needs
+// the user's stored hashes.
+type PasswordPolicy struct {
+	cfg      *config.PasswordPolicyConfig
+	breached *BreachedPasswordList
+}
+
+func NewPasswordPolicy(cfg *config.PasswordPolicyConfig) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{cfg: cfg}
+
+	if cfg.BreachedListPath != "" {
+		breached, err := OpenBreachedPasswordList(cfg.BreachedListPath)
+		if err != nil {
+			return nil, err
+		}
+		policy.breached = breached
+	}
+
+	return policy, nil
+}
+
+// Check returns a message for every rule the password breaks. personal holds the
+// user's email and names, which must not appear in the password.
+func (p *PasswordPolicy) Check(password string, personal ...string) ([]string, error) {
+	var violations []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.cfg.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters", p.cfg.MinLength))
+	}
+	if length > p.cfg.MaxLength {
+		violations = append(violations, fmt.Sprintf("must not exceed %d characters", p.cfg.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsLetter(r):
+			hasSymbol = true
+		}
+	}
+	if p.cfg.RequireUpper && !hasUpper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if p.cfg.RequireLower && !hasLower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if p.cfg.RequireDigit && !hasDigit {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.cfg.RequireSymbol && !hasSymbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	if p.cfg.DisallowPersonalInfo && containsPersonalInfo(password, personal) {
+		violations = append(violations, "must not contain your name or email address")
+	}
+
+	if p.breached != nil {
+		count, err := p.breached.Count(password)
+		if err != nil {
+			return nil, err
+		}
+		if count >= p.cfg.BreachedMinCount {
+			violations = append(violations, "has appeared in a data breach and cannot be used")
+		}
+	}
+
+	return violations, nil
+}
+
+func containsPersonalInfo(password string, personal []string) bool {
+	lowered := strings.ToLower(password)
+
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if at := strings.IndexByte(value, '@'); at >= 0 {
+			value = value[:at]
+		}
+		// Very short fragments match too many unrelated passwords
+		if utf8.RuneCountInString(value) < 3 {
+			continue
+		}
+		if strings.Contains(lowered, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// BreachedPasswordList looks up passwords in a file of uppercase SHA-1 hashes in the
+// HIBP download format ("HASH:COUNT" per line, sorted by hash). The file is binary
+// searched in place, so multi-gigabyte lists need no memory.
+type BreachedPasswordList struct {
+	file *os.File
+	size int64
+}
+
+func OpenBreachedPasswordList(path string) (*BreachedPasswordList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open breached password list: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("stat breached password list: %w", err)
+	}
+
+	return &BreachedPasswordList{file: file, size: info.Size()}, nil
+}
+
+// Count returns how often the password appears in the list, 0 when it does not
+func (l *BreachedPasswordList) Count(password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	target := []byte(strings.ToUpper(hex.EncodeToString(sum[:])))
+
+	// lo is always the start of a line, candidate lines start in [lo, hi)
+	lo, hi := int64(0), l.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+
+		start, err := l.lineStartFrom(mid)
+		if err != nil {
+			return 0, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+
+		line, next, err := l.readLine(start)
+		if err != nil {
+			return 0, err
+		}
+
+		hash, count, _ := bytes.Cut(line, []byte(":"))
+		switch bytes.Compare(bytes.ToUpper(hash), target) {
+		case 0:
+			n, err := strconv.Atoi(string(bytes.TrimSpace(count)))
+			if err != nil {
+				return 1, nil
+			}
+			return n, nil
+		case -1:
+			lo = next
+		default:
+			hi = mid
+		}
+	}
+
+	return 0, nil
+}
+
+// lineStartFrom returns the offset of the first line starting at or after off
+func (l *BreachedPasswordList) lineStartFrom(off int64) (int64, error) {
+	if off == 0 {
+		return 0, nil
+	}
+
+	buf := make([]byte, 128)
+	for pos := off - 1; pos < l.size; pos += int64(len(buf)) {
+		n, err := l.file.ReadAt(buf, pos)
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return pos + int64(i) + 1, nil
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return l.size, nil
+}
+
+// readLine returns the line starting at off without its line ending, and the offset of the next line
+func (l *BreachedPasswordList) readLine(off int64) ([]byte, int64, error) {
+	buf := make([]byte, 128)
+	n, err := l.file.ReadAt(buf, off)
+	if err != nil && err != io.EOF {
+		return nil, 0, err
+	}
+	buf = buf[:n]
+
+	if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+		return bytes.TrimRight(buf[:i], "\r"), off + int64(i) + 1, nil
+	}
+
+	return bytes.TrimRight(buf, "\r"), off + int64(n), nil
+}
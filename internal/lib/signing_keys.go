@@ -0,0 +1,237 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is a private key access and refresh tokens are signed with. Its kid is
+// the RFC 7638 thumbprint of the public key.
+type SigningKey struct {
+	ID     string
+	File   string
+	Method jwt.SigningMethod
+	Key    crypto.Signer
+}
+
+// SigningKeys are the keys in a directory of PKCS#8 PEM files. All of them are
+// published in the JWKS and accepted, and tokens are signed with the newest, the
+// last file in name order.
+type SigningKeys struct {
+	keys []*SigningKey
+}
+
+func LoadSigningKeys(dir string) (*SigningKeys, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(files)
+
+	keys := &SigningKeys{}
+	for _, file := range files {
+		key, err := readSigningKey(file)
+		if err != nil {
+			return nil, err
+		}
+		keys.keys = append(keys.keys, key)
+	}
+
+	return keys, nil
+}
+
+// Current returns the key new tokens are signed with, nil when there are none
+func (k *SigningKeys) Current() *SigningKey {
+	if len(k.keys) == 0 {
+		return nil
+	}
+	return k.keys[len(k.keys)-1]
+}
+
+// All returns the keys oldest first
+func (k *SigningKeys) All() []*SigningKey {
+	return k.keys
+}
+
+// PublicKey returns the public key for a kid
+func (k *SigningKeys) PublicKey(kid string) (crypto.PublicKey, bool) {
+	for _, key := range k.keys {
+		if key.ID == kid {
+			return key.Key.Public(), true
+		}
+	}
+	return nil, false
+}
+
+// JWKS returns the public keys as a JSON Web Key Set (RFC 7517)
+func (k *SigningKeys) JWKS() jose.JSONWebKeySet {
+	set := jose.JSONWebKeySet{Keys: make([]jose.JSONWebKey, 0, len(k.keys))}
+	for _, key := range k.keys {
+		set.Keys = append(set.Keys, jose.JSONWebKey{
+			Key:       key.Key.Public(),
+			KeyID:     key.ID,
+			Algorithm: key.Method.Alg(),
+			Use:       "sig",
+		})
+	}
+	return set
+}
+
+// readSigningKey loads an RSA (RS256), ECDSA (ES256, ES384 or ES512 by curve) or
+// Ed25519 (EdDSA) PKCS#8 key
+func readSigningKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s contains no PEM data", path)
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a PKCS#8 private key", path)
+	}
+
+	key := &SigningKey{File: path}
+	switch private := parsed.(type) {
+	case *rsa.PrivateKey:
+		if _, err := checkRSAKeySize(private); err != nil {
+			return nil, err
+		}
+		key.Method, key.Key = jwt.SigningMethodRS256, private
+	case *ecdsa.PrivateKey:
+		switch private.Curve {
+		case elliptic.P256():
+			key.Method = jwt.SigningMethodES256
+		case elliptic.P384():
+			key.Method = jwt.SigningMethodES384
+		case elliptic.P521():
+			key.Method = jwt.SigningMethodES512
+		default:
+			return nil, fmt.Errorf("%s uses an unsupported curve", path)
+		}
+		key.Key = private
+	case ed25519.PrivateKey:
+		key.Method, key.Key = jwt.SigningMethodEdDSA, private
+	default:
+		return nil, fmt.Errorf("%s is not an RSA, ECDSA or Ed25519 key", path)
+	}
+
+	thumbprint, err := (&jose.JSONWebKey{Key: key.Key.Public()}).Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	key.ID = base64.RawURLEncoding.EncodeToString(thumbprint)
+
+	return key, nil
+}
+
+// signingKeyFileTime is the layout of key file names, their creation time in UTC
+const signingKeyFileTime = "20060102T150405Z"
+
+// GenerateSigningKey writes a new ES256, RS256 or EdDSA key to dir. Files are named
+// by creation time, so the new key sorts last and becomes the current one.
+func GenerateSigningKey(dir, alg string) (*SigningKey, error) {
+	var private crypto.Signer
+	var err error
+	switch alg {
+	case jwt.SigningMethodES256.Alg():
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case jwt.SigningMethodRS256.Alg():
+		private, err = rsa.GenerateKey(rand.Reader, 3072)
+	case jwt.SigningMethodEdDSA.Alg():
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %s", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, time.Now().UTC().Format(signingKeyFileTime)+".pem")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	return readSigningKey(path)
+}
+
+// PruneSigningKeys deletes old keys in dir, keeping at least the newest keep, and
+// returns the files deleted. A key is only deleted once it was replaced by a newer
+// one at least retiredFor ago, so tokens it signed have expired; the current key
+// is never deleted.
+func PruneSigningKeys(dir string, keep int, retiredFor time.Duration) ([]string, error) {
+	keys, err := LoadSigningKeys(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	for i := 0; i < len(keys.keys)-max(keep, 1); i++ {
+		// Keys stop signing when the next one is generated, and are retired in order
+		replaced, err := signingKeyCreatedAt(keys.keys[i+1].File)
+		if err != nil {
+			return deleted, err
+		}
+		if time.Since(replaced) < retiredFor {
+			break
+		}
+
+		if err := os.Remove(keys.keys[i].File); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, keys.keys[i].File)
+	}
+
+	return deleted, nil
+}
+
+// signingKeyCreatedAt reads the creation time GenerateSigningKey names files by,
+// falling back to the modification time for keys added by hand
+func signingKeyCreatedAt(file string) (time.Time, error) {
+	name := strings.TrimSuffix(filepath.Base(file), ".pem")
+	if created, err := time.Parse(signingKeyFileTime, name); err == nil {
+		return created, nil
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
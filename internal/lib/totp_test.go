@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// The RFC 6238 Appendix B secret, the ASCII "12345678901234567890", base32 encoded
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// RFC 6238 Appendix B, SHA1. The RFC's codes have 8 digits, ours are their last 6.
+var rfc6238Vectors = []struct {
+	unix int64
+	step int64
+	code string
+}{
+	{59, 0x1, "287082"},
+	{1111111109, 0x23523EC, "081804"},
+	{1111111111, 0x23523ED, "050471"},
+	{1234567890, 0x273EF07, "005924"},
+	{2000000000, 0x3F940AA, "279037"},
+	{20000000000, 0x27BC86AA, "353130"},
+}
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		at := time.Unix(v.unix, 0)
+		if step := TOTPStep(at); step != v.step {
+			t.Errorf("%d: step = %#x, want %#x", v.unix, step, v.step)
+		}
+
+		code, err := TOTPCode(rfc6238Secret, v.step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != v.code {
+			t.Errorf("%d: code = %s, want %s", v.unix, code, v.code)
+		}
+
+		step, ok := ValidateTOTP(rfc6238Secret, v.code, at)
+		if !ok || step != v.step {
+			t.Errorf("%d: ValidateTOTP = %#x, %v, want %#x, true", v.unix, step, ok, v.step)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+	current := TOTPStep(at)
+
+	for offset := int64(-3); offset <= 3; offset++ {
+		code, err := TOTPCode(rfc6238Secret, current+offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		step, ok := ValidateTOTP(rfc6238Secret, code, at)
+		inWindow := offset >= -TOTPSkew && offset <= TOTPSkew
+		if ok != inWindow {
+			t.Errorf("offset %d: accepted = %v, want %v", offset, ok, inWindow)
+		}
+		if ok && step != current+offset {
+			t.Errorf("offset %d: step = %d, want %d", offset, step, current+offset)
+		}
+	}
+}
+
+func TestValidateTOTPInput(t *testing.T) {
+	at := time.Unix(59, 0)
+
+	if _, ok := ValidateTOTP(rfc6238Secret, " 287082 ", at); !ok {
+		t.Error("code with surrounding spaces rejected")
+	}
+	if _, ok := ValidateTOTP(strings.ToLower(rfc6238Secret), "287082", at); !ok {
+		t.Error("lower case secret rejected")
+	}
+
+	for _, code := range []string{"", "28708", "2870820", "94287082", "287083", "abcdef"} {
+		if _, ok := ValidateTOTP(rfc6238Secret, code, at); ok {
+			t.Errorf("code %q accepted", code)
+		}
+	}
+
+	if _, ok := ValidateTOTP("not base32!", "287082", at); ok {
+		t.Error("invalid secret accepted")
+	}
+}
+
+// TestValidateTOTPReplay plays the caller's part: a step is only accepted above the
+// last one used, as MFARepository.UseTOTPStep enforces
+func TestValidateTOTPReplay(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+	current := TOTPStep(at)
+	lastUsed := int64(0)
+
+	use := func(code string, at time.Time) bool {
+		step, ok := ValidateTOTP(rfc6238Secret, code, at)
+		if !ok || step <= lastUsed {
+			return false
+		}
+		lastUsed = step
+		return true
+	}
+
+	currentCode, _ := TOTPCode(rfc6238Secret, current)
+	previousCode, _ := TOTPCode(rfc6238Secret, current-1)
+	nextCode, _ := TOTPCode(rfc6238Secret, current+1)
+
+	if !use(currentCode, at) {
+		t.Fatal("current code rejected")
+	}
+	if use(currentCode, at) {
+		t.Error("current code replayed")
+	}
+	if use(currentCode, at.Add(TOTPPeriod*time.Second)) {
+		t.Error("current code replayed in the next period, inside the skew window")
+	}
+	if use(previousCode, at) {
+		t.Error("code of an earlier step accepted after a later one")
+	}
+	if !use(nextCode, at) {
+		t.Error("code of the next step rejected")
+	}
+}
@@ -0,0 +1,17 @@
+package logger
+
+import "context"
+
+type requestIDKey struct{}
+
+// WithRequestID stores the request id in the context, for code without access to
+// the echo context such as the database tracer
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request id stored by WithRequestID
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
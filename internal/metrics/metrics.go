@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "jwt"
+
+// Metrics holds the application's Prometheus collectors, registered on a registry
+// of their own
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	// HTTPRequests and HTTPRequestDuration are by route template, not request
+	// path, to keep ids out of the labels
+	HTTPRequests        *prometheus.CounterVec
+	HTTPRequestDuration *prometheus.HistogramVec
+	// Logins counts login attempts by method, result and failure reason
+	Logins *prometheus.CounterVec
+	// TokensIssued counts tokens by type (access or refresh) and format
+	TokensIssued  *prometheus.CounterVec
+	RateLimitHits *prometheus.CounterVec
+	// DBQueryDuration is the latency of database queries by query name, like
+	// "select users"
+	DBQueryDuration *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		HTTPRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		HTTPRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of HTTP requests by method and route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		Logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Login attempts by method, result and failure reason.",
+		}, []string{"method", "result", "reason"}),
+		TokensIssued: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tokens_issued_total",
+			Help:      "Tokens issued by type and format.",
+		}, []string{"type", "format"}),
+		RateLimitHits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limit_hits_total",
+			Help:      "Requests rejected by the rate limiter by route.",
+		}, []string{"path"}),
+		DBQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "Latency of database queries by query name.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"query", "status"}),
+	}
+
+	m.Registry.MustRegister(
+		m.HTTPRequests,
+		m.HTTPRequestDuration,
+		m.Logins,
+		m.TokensIssued,
+		m.RateLimitHits,
+		m.DBQueryDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
@@ -1,10 +1,18 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/2SSK/jwt/internal/errs"
+	utils "github.com/2SSK/jwt/internal/lib"
+	"github.com/2SSK/jwt/internal/model/user"
 	"github.com/2SSK/jwt/internal/server"
 	"github.com/2SSK/jwt/internal/service"
 	"github.com/golang-jwt/jwt/v5"
@@ -25,99 +33,343 @@ func (auth *AuthMiddleware) RequireRole(role string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			// First, require authentication
-			authHeader := c.Request().Header.Get("Authorization")
-			if authHeader == "" {
-				return echo.NewHTTPError(http.StatusUnauthorized, "missing authorization header")
+			userID, err := auth.authenticate(c, service.TokenTypeAccess)
+			if err != nil {
+				return err
 			}
 
-			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-			if tokenString == authHeader {
-				return echo.NewHTTPError(http.StatusUnauthorized, "invalid authorization header format")
+			// Check user role using AuthHelper
+			err = auth.services.AuthHelper.CheckUserType(c.Request().Context(), userID, role)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusForbidden, "insufficient permissions")
 			}
 
-			token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, errors.New("unexpected signing method")
-				}
-				return []byte(auth.server.Config.Auth.SecretKey), nil
-			})
+			return next(c)
+		}
+	}
+}
 
-			if err != nil || !token.Valid {
-				return echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
+func (auth *AuthMiddleware) RequireAuth() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if _, err := auth.authenticate(c, service.TokenTypeAccess); err != nil {
+				return err
 			}
 
-			claims, ok := token.Claims.(jwt.MapClaims)
-			if !ok {
-				return echo.NewHTTPError(http.StatusUnauthorized, "invalid token claims")
-			}
+			return next(c)
+		}
+	}
+}
 
-			userIDStr, ok := claims["user_id"].(string)
-			if !ok {
-				return echo.NewHTTPError(http.StatusUnauthorized, "invalid user ID in token")
+// RequireRefreshToken guards the refresh endpoint, which only accepts refresh tokens.
+// Without an Authorization header it falls back to the refresh cookie set for
+// browser clients, which additionally requires the CSRF token in the CSRF header
+// and cookie (double submit).
+func (auth *AuthMiddleware) RequireRefreshToken() echo.MiddlewareFunc {
+	cfg := auth.server.Config.RefreshCookie
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			cookie, err := c.Cookie(cfg.Name)
+			if c.Request().Header.Get("Authorization") != "" || err != nil || cookie.Value == "" {
+				if _, err := auth.authenticate(c, service.TokenTypeRefresh); err != nil {
+					return err
+				}
+				return next(c)
 			}
 
-			userID, err := uuid.Parse(userIDStr)
-			if err != nil {
-				return echo.NewHTTPError(http.StatusUnauthorized, "invalid user ID format")
+			if !auth.validCSRF(c, cookie.Value) {
+				return echo.NewHTTPError(http.StatusForbidden, "invalid CSRF token")
 			}
 
-			// Check user role using AuthHelper
-			err = auth.services.AuthHelper.CheckUserType(c.Request().Context(), userID, role)
-			if err != nil {
-				return echo.NewHTTPError(http.StatusForbidden, "insufficient permissions")
+			if _, err := auth.authenticateToken(c, cookie.Value, service.TokenTypeRefresh, ""); err != nil {
+				return err
 			}
-
-			// Set user ID in context for handlers to use
-			c.Set("user_id", userID)
+			c.Set(RefreshCookieKey, true)
 
 			return next(c)
 		}
 	}
 }
 
-func (auth *AuthMiddleware) RequireAuth() echo.MiddlewareFunc {
+// validCSRF checks that the CSRF header matches the CSRF cookie and was derived
+// from the refresh token, so it cannot come from a cross-site form or a cookie
+// planted by another subdomain
+func (auth *AuthMiddleware) validCSRF(c echo.Context, refreshToken string) bool {
+	cfg := auth.server.Config.RefreshCookie
+
+	header := c.Request().Header.Get(cfg.CSRFHeader)
+	cookie, err := c.Cookie(cfg.CSRFCookieName)
+	if header == "" || err != nil || subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) != 1 {
+		return false
+	}
+
+	return utils.ValidCSRFToken(auth.server.Config.Auth.SecretKey, refreshToken, header)
+}
+
+// RequireRecentAuth guards sensitive routes, it must run after RequireAuth or
+// RequireRole. The user must have logged in or re-authenticated within maxAge, at
+// least at the given level (see session.LevelSingleFactor); otherwise the response
+// asks them to re-authenticate, in the style of RFC 9470.
+func (auth *AuthMiddleware) RequireRecentAuth(maxAge time.Duration, level int) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			authHeader := c.Request().Header.Get("Authorization")
-			if authHeader == "" {
-				return echo.NewHTTPError(http.StatusUnauthorized, "missing authorization header")
-			}
+			authTime, _ := c.Get(AuthTimeKey).(time.Time)
+			acr, _ := c.Get(ACRKey).(int)
 
-			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-			if tokenString == authHeader {
-				return echo.NewHTTPError(http.StatusUnauthorized, "invalid authorization header format")
+			if acr < level || time.Since(authTime) > maxAge {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, fmt.Sprintf(
+					`Bearer error="insufficient_user_authentication", error_description="re-authentication required", acr_values="%d", max_age=%d`,
+					level, int(maxAge.Seconds()),
+				))
+				return errs.NewReauthenticationRequiredError("please re-authenticate to continue")
 			}
 
-			token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, errors.New("unexpected signing method")
-				}
-				return []byte(auth.server.Config.Auth.SecretKey), nil
-			})
+			return next(c)
+		}
+	}
+}
+
+// RequireStepUp is RequireRecentAuth with the configured step-up age and level
+func (auth *AuthMiddleware) RequireStepUp() echo.MiddlewareFunc {
+	cfg := auth.server.Config.Sessions
+	return auth.RequireRecentAuth(cfg.StepUpMaxAge, cfg.StepUpLevel)
+}
 
-			if err != nil || !token.Valid {
-				return echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
+// DPoPProof goes on routes that issue tokens for a new session. When the request
+// carries a valid DPoP proof, the session and its tokens are bound to the proof's
+// key; without one they are plain bearer tokens.
+func (auth *AuthMiddleware) DPoPProof() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !auth.server.Config.DPoP.Enabled || c.Request().Header.Get(dpopHeader) == "" {
+				return next(c)
 			}
 
-			claims, ok := token.Claims.(jwt.MapClaims)
-			if !ok {
-				return echo.NewHTTPError(http.StatusUnauthorized, "invalid token claims")
+			jkt, err := auth.verifyDPoPProof(c, "")
+			if err != nil {
+				return err
 			}
+			c.Set(DPoPJKTKey, jkt)
+
+			return next(c)
+		}
+	}
+}
 
-			userIDStr, ok := claims["user_id"].(string)
+// TLSClientAuth goes on routes that issue tokens for a new session. Clients
+// registered for tls_client_auth (see config.TLSConfig) that identify themselves
+// with X-Client-Id must have presented their certificate on the connection.
+func (auth *AuthMiddleware) TLSClientAuth() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			clientID := strings.ToLower(strings.TrimSpace(c.Request().Header.Get("X-Client-Id")))
+			registered, ok := auth.server.Config.TLS.Clients[clientID]
 			if !ok {
-				return echo.NewHTTPError(http.StatusUnauthorized, "invalid user ID in token")
+				return next(c)
 			}
 
-			userID, err := uuid.Parse(userIDStr)
-			if err != nil {
-				return echo.NewHTTPError(http.StatusUnauthorized, "invalid user ID format")
+			cert := utils.ClientCertificate(c.Request().TLS)
+			if cert == nil || !utils.MatchesTLSClientAuth(cert, registered) {
+				return errs.NewUnauthorizedError("client certificate does not match the registered client", true)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// RequireIntrospectionClient lets through resource servers registered for
+// tls_client_auth and listed in config.TokensConfig.IntrospectionClients, which
+// present their certificate and X-Client-Id, and otherwise requires an admin
+func (auth *AuthMiddleware) RequireIntrospectionClient() echo.MiddlewareFunc {
+	requireAdmin := auth.RequireRole("admin")
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		adminOnly := requireAdmin(next)
+
+		return func(c echo.Context) error {
+			clientID := strings.ToLower(strings.TrimSpace(c.Request().Header.Get("X-Client-Id")))
+			registered, ok := auth.server.Config.TLS.Clients[clientID]
+			if !ok || !slices.Contains(auth.server.Config.Tokens.IntrospectionClients, clientID) {
+				return adminOnly(c)
 			}
 
-			// Set user ID in context for handlers to use
-			c.Set("user_id", userID)
+			cert := utils.ClientCertificate(c.Request().TLS)
+			if cert == nil || !utils.MatchesTLSClientAuth(cert, registered) {
+				return errs.NewUnauthorizedError("client certificate does not match the registered client", true)
+			}
 
 			return next(c)
 		}
 	}
 }
+
+// authenticate validates a token of the given type from the Authorization header,
+// sent with the Bearer or, for DPoP-bound tokens, the DPoP scheme
+func (auth *AuthMiddleware) authenticate(c echo.Context, tokenType string) (uuid.UUID, error) {
+	authHeader := c.Request().Header.Get("Authorization")
+	if authHeader == "" {
+		return uuid.Nil, echo.NewHTTPError(http.StatusUnauthorized, "missing authorization header")
+	}
+
+	scheme, tokenString, _ := strings.Cut(authHeader, " ")
+	if tokenString == "" || (scheme != user.TokenTypeBearer && scheme != user.TokenTypeDPoP) {
+		return uuid.Nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid authorization header format")
+	}
+
+	return auth.authenticateToken(c, tokenString, tokenType, scheme)
+}
+
+// authenticateToken validates a token of the given type and the session it was
+// issued for, then sets the user and session IDs in context for handlers to use.
+// scheme is the Authorization scheme the token came with, empty for the refresh
+// cookie.
+func (auth *AuthMiddleware) authenticateToken(c echo.Context, tokenString, tokenType, scheme string) (uuid.UUID, error) {
+	claims, err := auth.services.Token.Parse(c.Request().Context(), tokenString)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidToken) {
+			return uuid.Nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
+		}
+		return uuid.Nil, err
+	}
+
+	if typ, _ := claims["typ"].(string); typ != tokenType {
+		return uuid.Nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid token type")
+	}
+
+	if err := auth.checkDPoPBinding(c, claims, tokenString, scheme); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := auth.checkCertificateBinding(c, claims); err != nil {
+		return uuid.Nil, err
+	}
+
+	userIDStr, ok := claims["user_id"].(string)
+	if !ok {
+		return uuid.Nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid user ID in token")
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid user ID format")
+	}
+
+	// Tokens issued before sessions existed carry no sid and must be replaced by logging in again
+	sessionIDStr, ok := claims["sid"].(string)
+	if !ok {
+		return uuid.Nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid session in token")
+	}
+
+	sessionID, err := uuid.Parse(sessionIDStr)
+	if err != nil {
+		return uuid.Nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid session ID format")
+	}
+
+	if err := auth.services.Session.Authenticate(c.Request().Context(), userID, sessionID); err != nil {
+		return uuid.Nil, err
+	}
+
+	c.Set(UserIDKey, userID)
+	c.Set(SessionIDKey, sessionID)
+
+	// Tokens issued before step-up support carry neither, and never count as recent
+	if authTime, ok := claims["auth_time"].(float64); ok {
+		c.Set(AuthTimeKey, time.Unix(int64(authTime), 0))
+	}
+	if acr, ok := claims["acr"].(string); ok {
+		if level, err := strconv.Atoi(acr); err == nil {
+			c.Set(ACRKey, level)
+		}
+	}
+
+	return userID, nil
+}
+
+// dpopHeader carries the DPoP proof
+const dpopHeader = "DPoP"
+
+// checkDPoPBinding enforces RFC 9449 for tokens with a cnf.jkt claim: they must be
+// sent with the DPoP scheme and a fresh proof signed by the bound key, which also
+// hashes the token unless it came from the refresh cookie. Unbound tokens must not
+// use the DPoP scheme.
+func (auth *AuthMiddleware) checkDPoPBinding(c echo.Context, claims jwt.MapClaims, tokenString, scheme string) error {
+	cnf, _ := claims["cnf"].(map[string]interface{})
+	jkt, _ := cnf["jkt"].(string)
+
+	if jkt == "" {
+		if scheme == user.TokenTypeDPoP {
+			auth.setDPoPChallenge(c, "invalid_token")
+			return errs.NewUnauthorizedError("token is not bound to a DPoP key", true)
+		}
+		return nil
+	}
+
+	if scheme == user.TokenTypeBearer {
+		auth.setDPoPChallenge(c, "invalid_token")
+		return errs.NewUnauthorizedError("DPoP-bound tokens must use the DPoP authorization scheme", true)
+	}
+
+	accessToken := tokenString
+	if scheme == "" {
+		accessToken = ""
+	}
+
+	thumbprint, err := auth.verifyDPoPProof(c, accessToken)
+	if err != nil {
+		return err
+	}
+	if thumbprint != jkt {
+		auth.setDPoPChallenge(c, "invalid_dpop_proof")
+		return errs.NewInvalidDPoPProofError("DPoP proof was not signed by the key the token is bound to")
+	}
+	c.Set(DPoPJKTKey, thumbprint)
+
+	return nil
+}
+
+// checkCertificateBinding enforces RFC 8705 for tokens with a cnf.x5t#S256 claim:
+// they are only accepted over a connection with the client certificate they were
+// issued to
+func (auth *AuthMiddleware) checkCertificateBinding(c echo.Context, claims jwt.MapClaims) error {
+	cnf, _ := claims["cnf"].(map[string]interface{})
+	thumbprint, _ := cnf["x5t#S256"].(string)
+	if thumbprint == "" {
+		return nil
+	}
+
+	cert := utils.ClientCertificate(c.Request().TLS)
+	if cert == nil || subtle.ConstantTimeCompare([]byte(utils.CertificateThumbprint(cert)), []byte(thumbprint)) != 1 {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer error="invalid_token", error_description="certificate-bound token"`)
+		return errs.NewUnauthorizedError("token is bound to a client certificate that was not presented", true)
+	}
+
+	return nil
+}
+
+// verifyDPoPProof checks the request's single DPoP proof against its method and URL
+func (auth *AuthMiddleware) verifyDPoPProof(c echo.Context, accessToken string) (string, error) {
+	req := c.Request()
+
+	proofs := req.Header.Values(dpopHeader)
+	if len(proofs) != 1 {
+		auth.setDPoPChallenge(c, "invalid_dpop_proof")
+		return "", errs.NewInvalidDPoPProofError("exactly one DPoP proof is required")
+	}
+
+	target := c.Scheme() + "://" + req.Host + req.URL.Path
+	thumbprint, err := auth.services.DPoP.VerifyProof(proofs[0], req.Method, target, accessToken)
+	if err != nil {
+		auth.setDPoPChallenge(c, "invalid_dpop_proof")
+		return "", err
+	}
+
+	return thumbprint, nil
+}
+
+// setDPoPChallenge tells the client which proof algorithms are accepted
+func (auth *AuthMiddleware) setDPoPChallenge(c echo.Context, code string) {
+	c.Response().Header().Set(echo.HeaderWWWAuthenticate, fmt.Sprintf(
+		`DPoP error="%s", algs="%s"`, code, strings.Join(auth.server.Config.DPoP.Algorithms, " "),
+	))
+}
@@ -6,12 +6,20 @@ import (
 	"github.com/2SSK/jwt/internal/server"
 	"github.com/labstack/echo/v4"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	UserIDKey   = "user_id"
-	UserRoleKey = "user_role"
-	LoggerKey   = "logger"
+	UserIDKey    = "user_id"
+	SessionIDKey = "session_id"
+	AuthTimeKey  = "auth_time"
+	ACRKey       = "acr"
+	// DPoPJKTKey holds the key thumbprint of the request's valid DPoP proof
+	DPoPJKTKey = "dpop_jkt"
+	// RefreshCookieKey is set when a refresh was authenticated by the refresh cookie
+	RefreshCookieKey = "refresh_cookie"
+	UserRoleKey      = "user_role"
+	LoggerKey        = "logger"
 )
 
 type ContextEnhancer struct {
@@ -45,6 +53,14 @@ func (ce *ContextEnhancer) EnhanceContext() echo.MiddlewareFunc {
 				contextLogger = contextLogger.With().Str("user_role", userRole).Logger()
 			}
 
+			// Link the logs to the request's trace
+			if spanContext := trace.SpanContextFromContext(c.Request().Context()); spanContext.IsValid() {
+				contextLogger = contextLogger.With().
+					Str("trace_id", spanContext.TraceID().String()).
+					Str("span_id", spanContext.SpanID().String()).
+					Logger()
+			}
+
 			// Store the enhanced logger in context
 			c.Set(LoggerKey, &contextLogger)
 
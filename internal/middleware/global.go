@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
+	"strconv"
 
 	"github.com/2SSK/jwt/internal/errs"
 	"github.com/2SSK/jwt/internal/server"
@@ -23,8 +25,12 @@ func NewGlobalMiddlewares(s *server.Server) *GlobalMiddlewares {
 }
 
 func (global *GlobalMiddlewares) CORS() echo.MiddlewareFunc {
+	origins := global.server.Config.Server.CORSAllowedOrigins
 	return middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: global.server.Config.Server.CORSAllowedOrigins,
+		AllowOrigins: origins,
+		// Browser clients on an allowed origin send the refresh cookie, which is
+		// never allowed for the wildcard origin
+		AllowCredentials: !slices.Contains(origins, "*"),
 	})
 }
 
@@ -126,6 +132,7 @@ func (global *GlobalMiddlewares) GlobalErrorHandler(err error, c echo.Context) {
 	var message string
 	var fieldErrors []errs.FieldError
 	var action *errs.Action
+	var retryAfter int
 
 	switch {
 	case errors.As(err, &httpErr):
@@ -134,6 +141,7 @@ func (global *GlobalMiddlewares) GlobalErrorHandler(err error, c echo.Context) {
 		message = httpErr.Message
 		fieldErrors = httpErr.Errors
 		action = httpErr.Action
+		retryAfter = httpErr.RetryAfter
 
 	case errors.As(err, &echoErr):
 		status = echoErr.Code
@@ -162,13 +170,18 @@ func (global *GlobalMiddlewares) GlobalErrorHandler(err error, c echo.Context) {
 		Msg(message)
 
 	if !c.Response().Committed {
+		if retryAfter > 0 {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		}
+
 		_ = c.JSON(status, errs.HTTPError{
-			Code:     code,
-			Message:  message,
-			Status:   status,
-			Override: httpErr != nil && httpErr.Override,
-			Errors:   fieldErrors,
-			Action:   action,
+			Code:       code,
+			Message:    message,
+			Status:     status,
+			Override:   httpErr != nil && httpErr.Override,
+			Errors:     fieldErrors,
+			Action:     action,
+			RetryAfter: retryAfter,
 		})
 	}
 }
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/2SSK/jwt/internal/errs"
+	"github.com/2SSK/jwt/internal/server"
+	"github.com/2SSK/jwt/internal/sqlerr"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+type MetricsMiddleware struct {
+	server *server.Server
+}
+
+func NewMetricsMiddleware(s *server.Server) *MetricsMiddleware {
+	return &MetricsMiddleware{server: s}
+}
+
+// RecordRequests counts requests and their latency by route and status
+func (m *MetricsMiddleware) RecordRequests() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			method := c.Request().Method
+			status := strconv.Itoa(responseStatus(c, err))
+
+			m.server.Metrics.HTTPRequests.WithLabelValues(method, route, status).Inc()
+			m.server.Metrics.HTTPRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+
+			return err
+		}
+	}
+}
+
+// responseStatus is the status a request is answered with. Errors are only
+// written by the global error handler, after the middlewares have returned.
+func responseStatus(c echo.Context, err error) int {
+	if err == nil {
+		return c.Response().Status
+	}
+
+	var httpErr *errs.HTTPError
+	var echoErr *echo.HTTPError
+	switch {
+	case errors.As(err, &httpErr):
+		return httpErr.Status
+	case errors.As(err, &echoErr):
+		return echoErr.Code
+	case errors.As(sqlerr.HandleError(err), &httpErr):
+		return httpErr.Status
+	}
+	return http.StatusInternalServerError
+}
@@ -8,6 +8,8 @@ import (
 type Middlewares struct {
 	Global          *GlobalMiddlewares
 	RateLimit       *RateLimitMiddleware
+	Metrics         *MetricsMiddleware
+	Tracing         *TracingMiddleware
 	ContextEnhancer *ContextEnhancer
 	Auth            *AuthMiddleware
 }
@@ -17,6 +19,8 @@ func NewMiddlewares(s *server.Server, services *service.Services) *Middlewares {
 	return &Middlewares{
 		Global:          NewGlobalMiddlewares(s),
 		RateLimit:       NewRateLimitMiddleware(s),
+		Metrics:         NewMetricsMiddleware(s),
+		Tracing:         NewTracingMiddleware(s),
 		ContextEnhancer: NewContextEnhancer(s),
 		Auth:            NewAuthMiddleware(s, services),
 	}
@@ -15,5 +15,5 @@ func NewRateLimitMiddleware(s *server.Server) *RateLimitMiddleware {
 }
 
 func (r *RateLimitMiddleware) RecordRateLimitHit(endpoint string) {
-	// Rate limit hit recorded (logging removed as LoggerService not used)
+	r.server.Metrics.RateLimitHits.WithLabelValues(endpoint).Inc()
 }
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"github.com/2SSK/jwt/internal/logger"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 )
@@ -20,6 +21,7 @@ func RequestID() echo.MiddlewareFunc {
 
 			c.Set(RequestIDKey, requestID)
 			c.Response().Header().Set(RequestIDHeader, requestID)
+			c.SetRequest(c.Request().WithContext(logger.WithRequestID(c.Request().Context(), requestID)))
 
 			return next(c)
 		}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/2SSK/jwt/internal/server"
+	"github.com/2SSK/jwt/internal/tracing"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type TracingMiddleware struct {
+	server *server.Server
+}
+
+func NewTracingMiddleware(s *server.Server) *TracingMiddleware {
+	return &TracingMiddleware{server: s}
+}
+
+// Trace wraps each request in a server span, continuing the trace of an incoming
+// traceparent header. The span is stored in the request context for the handlers.
+func (t *TracingMiddleware) Trace() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+
+			ctx, span := tracing.Start(ctx, req.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", req.Method),
+					attribute.String("http.route", route),
+					attribute.String("url.path", req.URL.Path),
+					attribute.String("client.address", c.RealIP()),
+					attribute.String("user_agent.original", req.UserAgent()),
+				),
+			)
+			defer span.End()
+
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+
+			status := responseStatus(c, err)
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+				if err != nil {
+					span.RecordError(err)
+				}
+			}
+
+			return err
+		}
+	}
+}
+
+// TraceHandler wraps the rest of the request, route middlewares such as
+// authentication and the handler, in a "handler" span. Used on the route groups, it
+// separates their work from the global middlewares in the request span.
+func (t *TracingMiddleware) TraceHandler() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx, span := tracing.Start(c.Request().Context(), "handler")
+			defer span.End()
+
+			c.SetRequest(c.Request().WithContext(ctx))
+			err := next(c)
+
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+
+			return err
+		}
+	}
+}
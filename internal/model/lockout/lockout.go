@@ -0,0 +1,27 @@
+package lockout
+
+import "time"
+
+const (
+	// ScopeAccount counters are keyed by the lowercased login email, so unknown
+	// addresses are throttled exactly like real accounts
+	ScopeAccount = "account"
+	ScopeIP      = "ip"
+)
+
+// Throttle tracks recent failed logins for one account or client IP
+type Throttle struct {
+	Scope         string     `json:"scope" db:"scope"`
+	Subject       string     `json:"subject" db:"subject"`
+	Failures      int        `json:"failures" db:"failures"`
+	LastFailureAt time.Time  `json:"lastFailureAt" db:"last_failure_at"`
+	LockedUntil   *time.Time `json:"lockedUntil" db:"locked_until"`
+}
+
+// Blocked reports how long attempts must still wait, zero when they may proceed
+func (t *Throttle) Blocked(now time.Time) time.Duration {
+	if t == nil || t.LockedUntil == nil || !now.Before(*t.LockedUntil) {
+		return 0
+	}
+	return t.LockedUntil.Sub(now)
+}
@@ -0,0 +1,22 @@
+package loginhistory
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ----------------------------------------------------
+
+type EventResponse struct {
+	ID                uuid.UUID `json:"id"`
+	Method            string    `json:"method"`
+	Success           bool      `json:"success"`
+	FailureReason     *string   `json:"failureReason"`
+	IPAddress         string    `json:"ipAddress"`
+	UserAgent         string    `json:"userAgent"`
+	DeviceFingerprint string    `json:"deviceFingerprint"`
+	CreatedAt         time.Time `json:"createdAt"`
+}
+
+// ----------------------------------------------------
@@ -0,0 +1,44 @@
+package loginhistory
+
+import (
+	"github.com/2SSK/jwt/internal/model"
+	"github.com/google/uuid"
+)
+
+// Methods record the factor that completed (or failed) the login. Logins finished
+// through an MFA challenge use the mfa method constants.
+const (
+	MethodPassword  = "password"
+	MethodMagicLink = "magic_link"
+	MethodPasskey   = "passkey"
+)
+
+const (
+	FailureInvalidCredentials = "invalid_credentials"
+	FailureAccountLocked      = "account_locked"
+	FailureThrottled          = "throttled"
+	FailureInvalidMFACode     = "invalid_mfa_code"
+	FailureInvalidMagicCode   = "invalid_magic_link_code"
+)
+
+// Event is one login attempt. UserID is nil when the email did not match an account.
+type Event struct {
+	model.BaseWithId
+	UserID            *uuid.UUID `json:"userId" db:"user_id"`
+	Email             *string    `json:"email" db:"email"`
+	Method            string     `json:"method" db:"method"`
+	Success           bool       `json:"success" db:"success"`
+	FailureReason     *string    `json:"failureReason" db:"failure_reason"`
+	IPAddress         string     `json:"ipAddress" db:"ip_address"`
+	Network           string     `json:"network" db:"network"`
+	UserAgent         string     `json:"userAgent" db:"user_agent"`
+	DeviceFingerprint string     `json:"deviceFingerprint" db:"device_fingerprint"`
+	model.BaseWithCreatedAt
+}
+
+// Familiarity says whether a user has logged in successfully before, and from where
+type Familiarity struct {
+	HasHistory   bool
+	KnownDevice  bool
+	KnownNetwork bool
+}
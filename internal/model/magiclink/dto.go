@@ -0,0 +1,38 @@
+package magiclink
+
+import (
+	"github.com/go-playground/validator/v10"
+)
+
+// ----------------------------------------------------
+
+type RequestPayload struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func (p *RequestPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ----------------------------------------------------
+
+// VerifyPayload redeems either the emailed link token, or the email address and code
+type VerifyPayload struct {
+	Token string `json:"token,omitempty" validate:"required_without=Code"`
+	Email string `json:"email,omitempty" validate:"required_with=Code,omitempty,email"`
+	Code  string `json:"code,omitempty" validate:"required_without=Token,omitempty,len=6,numeric"`
+}
+
+func (p *VerifyPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ----------------------------------------------------
+
+type RequestResponse struct {
+	Message string `json:"message"`
+}
+
+// ----------------------------------------------------
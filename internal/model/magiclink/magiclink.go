@@ -0,0 +1,21 @@
+package magiclink
+
+import (
+	"time"
+
+	"github.com/2SSK/jwt/internal/model"
+	"github.com/google/uuid"
+)
+
+// MagicLink is an emailed passwordless login. It can be redeemed once, either
+// with the link token or with the short code sent alongside it.
+type MagicLink struct {
+	model.BaseWithId
+	UserID     uuid.UUID  `json:"userId" db:"user_id"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	CodeHash   string     `json:"-" db:"code_hash"`
+	Attempts   int        `json:"attempts" db:"attempts"`
+	ExpiresAt  time.Time  `json:"expiresAt" db:"expires_at"`
+	ConsumedAt *time.Time `json:"consumedAt" db:"consumed_at"`
+	model.BaseWithCreatedAt
+}
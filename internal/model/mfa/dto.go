@@ -0,0 +1,63 @@
+package mfa
+
+import (
+	"github.com/go-playground/validator/v10"
+)
+
+// ----------------------------------------------------
+
+type TOTPEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"`
+}
+
+// ----------------------------------------------------
+
+type CodePayload struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+func (p *CodePayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ----------------------------------------------------
+
+type DisableTOTPPayload struct {
+	Code         string `json:"code,omitempty" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recoveryCode,omitempty" validate:"required_without=Code"`
+}
+
+func (p *DisableTOTPPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ----------------------------------------------------
+
+type VerifyPayload struct {
+	MFAToken     string `json:"mfaToken" validate:"required"`
+	Code         string `json:"code,omitempty" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recoveryCode,omitempty" validate:"required_without=Code"`
+}
+
+func (p *VerifyPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ----------------------------------------------------
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// ----------------------------------------------------
+
+type StatusResponse struct {
+	TOTPEnabled            bool `json:"totpEnabled"`
+	RecoveryCodesRemaining int  `json:"recoveryCodesRemaining"`
+}
+
+// ----------------------------------------------------
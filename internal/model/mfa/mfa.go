@@ -0,0 +1,51 @@
+package mfa
+
+import (
+	"time"
+
+	"github.com/2SSK/jwt/internal/model"
+	"github.com/google/uuid"
+)
+
+const (
+	MethodTOTP         = "totp"
+	MethodRecoveryCode = "recovery_code"
+	MethodWebAuthn     = "webauthn"
+)
+
+type TOTP struct {
+	UserID uuid.UUID `json:"userId" db:"user_id"`
+	// Secret is encrypted at rest with the mfa encryption key KeyID names, see
+	// utils.EncryptStringWithKey
+	Secret       string     `json:"-" db:"secret"`
+	KeyID        string     `json:"-" db:"key_id"`
+	ConfirmedAt  *time.Time `json:"confirmedAt" db:"confirmed_at"`
+	LastUsedStep int64      `json:"-" db:"last_used_step"`
+	model.BaseWithCreatedAt
+	model.BaseWithUpdatedAt
+}
+
+func (t *TOTP) Confirmed() bool {
+	return t != nil && t.ConfirmedAt != nil
+}
+
+type RecoveryCode struct {
+	model.BaseWithId
+	UserID   uuid.UUID  `json:"userId" db:"user_id"`
+	CodeHash string     `json:"-" db:"code_hash"`
+	UsedAt   *time.Time `json:"usedAt" db:"used_at"`
+	model.BaseWithCreatedAt
+}
+
+// Challenge is the pending second step of a login that passed the first factor
+type Challenge struct {
+	model.BaseWithId
+	UserID uuid.UUID `json:"userId" db:"user_id"`
+	// FirstFactor is the login method that created the challenge
+	FirstFactor string     `json:"firstFactor" db:"first_factor"`
+	TokenHash   string     `json:"-" db:"token_hash"`
+	Attempts    int        `json:"attempts" db:"attempts"`
+	ExpiresAt   time.Time  `json:"expiresAt" db:"expires_at"`
+	ConsumedAt  *time.Time `json:"consumedAt" db:"consumed_at"`
+	model.BaseWithCreatedAt
+}
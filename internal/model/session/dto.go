@@ -0,0 +1,30 @@
+package session
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ----------------------------------------------------
+
+type SessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	ClientID   string    `json:"clientId"`
+	DeviceName string    `json:"deviceName"`
+	IPAddress  string    `json:"ipAddress"`
+	UserAgent  string    `json:"userAgent"`
+	// Current marks the session the request was made with
+	Current    bool      `json:"current"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// ----------------------------------------------------
+
+type RevokeSessionsResponse struct {
+	Revoked int `json:"revoked"`
+}
+
+// ----------------------------------------------------
@@ -0,0 +1,56 @@
+package session
+
+import (
+	"time"
+
+	"github.com/2SSK/jwt/internal/model"
+	"github.com/google/uuid"
+)
+
+// Authentication method references (RFC 8176) recorded in the amr claim
+const (
+	AMRPassword = "pwd"
+	AMROTP      = "otp"
+	AMRWebAuthn = "webauthn"
+	AMREmail    = "email"
+)
+
+// Authentication levels carried in the acr claim. A user verifying passkey counts
+// as multi-factor on its own.
+const (
+	LevelSingleFactor = 1
+	LevelMultiFactor  = 2
+)
+
+// Session is created at login and referenced by the sid claim of every token issued
+// for it. Revoking it invalidates those tokens on their next use.
+type Session struct {
+	model.BaseWithId
+	UserID uuid.UUID `json:"userId" db:"user_id"`
+	// ClientID is the configured client the session was created through, if any
+	ClientID   string    `json:"clientId" db:"client_id"`
+	DeviceName string    `json:"deviceName" db:"device_name"`
+	IPAddress  string    `json:"ipAddress" db:"ip_address"`
+	UserAgent  string    `json:"userAgent" db:"user_agent"`
+	LastSeenAt time.Time `json:"lastSeenAt" db:"last_seen_at"`
+	// ExpiresAt is the idle expiry, pushed back on every refresh up to AbsoluteExpiresAt
+	ExpiresAt         time.Time `json:"expiresAt" db:"expires_at"`
+	AbsoluteExpiresAt time.Time `json:"absoluteExpiresAt" db:"absolute_expires_at"`
+	// AuthTime, AMR and ACR describe the latest authentication, at login or re-authentication
+	AuthTime time.Time `json:"authTime" db:"auth_time"`
+	AMR      []string  `json:"amr" db:"amr"`
+	ACR      int       `json:"acr" db:"acr"`
+	// DPoPJKT is the thumbprint of the key the session's tokens are bound to (RFC
+	// 9449), empty for bearer tokens
+	DPoPJKT string `json:"dpopJkt" db:"dpop_jkt"`
+	// CertThumbprint is the SHA-256 thumbprint of the client certificate the
+	// session's tokens are bound to (RFC 8705), empty when none was presented
+	CertThumbprint string     `json:"certThumbprint" db:"cert_thumbprint"`
+	RevokedAt      *time.Time `json:"revokedAt" db:"revoked_at"`
+	model.BaseWithCreatedAt
+}
+
+// Active reports whether the session is neither revoked nor expired
+func (s *Session) Active(now time.Time) bool {
+	return s != nil && s.RevokedAt == nil && now.Before(s.ExpiresAt) && now.Before(s.AbsoluteExpiresAt)
+}
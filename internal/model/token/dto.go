@@ -0,0 +1,35 @@
+package token
+
+import (
+	"github.com/go-playground/validator/v10"
+)
+
+// ----------------------------------------------------
+
+// IntrospectPayload is an RFC 7662 introspection request, sent as a form or JSON
+type IntrospectPayload struct {
+	Token string `json:"token" form:"token" validate:"required"`
+}
+
+func (p *IntrospectPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ----------------------------------------------------
+
+// IntrospectionResponse follows RFC 7662: inactive tokens only report active false
+type IntrospectionResponse struct {
+	Active    bool              `json:"active"`
+	Sub       string            `json:"sub,omitempty"`
+	ClientID  string            `json:"client_id,omitempty"`
+	SessionID string            `json:"sid,omitempty"`
+	Typ       string            `json:"typ,omitempty"`
+	TokenType string            `json:"token_type,omitempty"`
+	Exp       int64             `json:"exp,omitempty"`
+	Iat       int64             `json:"iat,omitempty"`
+	AuthTime  int64             `json:"auth_time,omitempty"`
+	AMR       []string          `json:"amr,omitempty"`
+	ACR       string            `json:"acr,omitempty"`
+	Cnf       map[string]string `json:"cnf,omitempty"`
+}
@@ -0,0 +1,22 @@
+package token
+
+import (
+	"time"
+
+	"github.com/2SSK/jwt/internal/model"
+	"github.com/google/uuid"
+)
+
+// OpaqueToken holds the claims of a reference token. Only the token's hash is
+// stored, the token itself is only known to the client.
+type OpaqueToken struct {
+	model.BaseWithId
+	TokenHash string    `json:"-" db:"token_hash"`
+	TokenType string    `json:"tokenType" db:"token_type"`
+	UserID    uuid.UUID `json:"userId" db:"user_id"`
+	SessionID uuid.UUID `json:"sessionId" db:"session_id"`
+	// Claims are the same claims a JWT for the session would carry
+	Claims    map[string]any `json:"claims" db:"claims"`
+	ExpiresAt time.Time      `json:"expiresAt" db:"expires_at"`
+	model.BaseWithCreatedAt
+}
@@ -11,7 +11,7 @@ import (
 
 type AddUserPayload struct {
 	Email     string `json:"email" validate:"required,email"`
-	Password  string `json:"password" validate:"required,min=8"`
+	Password  string `json:"password" validate:"required"`
 	FirstName string `json:"firstName" validate:"required"`
 	LastName  string `json:"lastName" validate:"required"`
 	Phone     string `json:"phone,omitempty"`
@@ -37,6 +37,31 @@ func (p *LoginPayload) Validate() error {
 
 // ----------------------------------------------------
 
+// ChangePasswordPayload is sent by a signed in user. Length and strength rules are
+// checked by the password policy rather than tags, since they are configurable.
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required"`
+}
+
+func (p *ChangePasswordPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ----------------------------------------------------
+
+type ResetPasswordPayload struct {
+	NewPassword string `json:"newPassword" validate:"required"`
+}
+
+func (p *ResetPasswordPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ----------------------------------------------------
+
 type UserResponse struct {
 	ID        uuid.UUID `json:"id"`
 	FirstName *string   `json:"firstName"`
@@ -51,24 +76,61 @@ type UserResponse struct {
 // ----------------------------------------------------
 
 type LoginResponse struct {
-	User         UserResponse `json:"user"`
-	AccessToken  string       `json:"accessToken"`
-	RefreshToken string       `json:"refreshToken"`
+	User UserResponse `json:"user"`
+	TokenResponse
+}
+
+// ----------------------------------------------------
+
+// MFAChallengeResponse is returned by login instead of tokens when the user
+// has a second factor enrolled. MFAToken is exchanged at /auth/mfa/verify.
+type MFAChallengeResponse struct {
+	MFARequired bool     `json:"mfaRequired"`
+	MFAToken    string   `json:"mfaToken"`
+	Methods     []string `json:"methods"`
+	ExpiresIn   int      `json:"expiresIn"`
 }
 
 // ----------------------------------------------------
 
 type SignUpResponse struct {
-	User         UserResponse `json:"user"`
-	AccessToken  string       `json:"accessToken"`
-	RefreshToken string       `json:"refreshToken"`
+	User UserResponse `json:"user"`
+	TokenResponse
 }
 
 // ----------------------------------------------------
 
+// Token types, which tell clients how to present the access token
+const (
+	TokenTypeBearer = "Bearer"
+	// TokenTypeDPoP tokens are bound to a key and must be sent with a DPoP proof
+	TokenTypeDPoP = "DPoP"
+)
+
 type TokenResponse struct {
 	AccessToken  string `json:"accessToken"`
-	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	// CSRFToken replaces RefreshToken for browser clients, whose refresh token is
+	// set as a cookie instead. It must be sent back in the CSRF header on refresh.
+	CSRFToken string `json:"csrfToken,omitempty"`
+	// RefreshTokenExpiresAt sets the lifetime of the refresh cookie
+	RefreshTokenExpiresAt time.Time `json:"-"`
+}
+
+// ----------------------------------------------------
+
+// ReauthenticatePayload proves the identity of a signed in user again with their
+// password, a second factor, or both
+type ReauthenticatePayload struct {
+	Password     string `json:"password,omitempty" validate:"required_without_all=Code RecoveryCode"`
+	Code         string `json:"code,omitempty" validate:"omitempty,len=6,numeric,excluded_with=RecoveryCode"`
+	RecoveryCode string `json:"recoveryCode,omitempty"`
+}
+
+func (p *ReauthenticatePayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
 }
 
 // ----------------------------------------------------
@@ -13,3 +13,19 @@ type User struct {
 	UserType     *string `json:"userType" db:"user_type"`
 	RefreshToken *string `json:"-" db:"refresh_token"`
 }
+
+// ClientInfo describes the device and network a request came from
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+	// DeviceFingerprint is stable for a device across logins, see handler.clientInfo
+	DeviceFingerprint string
+	// DeviceName is the name the client gave itself, if any
+	DeviceName string
+	// ClientID identifies the application, it selects per-client token lifetimes
+	ClientID string
+	// DPoPJKT is the key thumbprint of a valid DPoP proof sent with the request
+	DPoPJKT string
+	// CertThumbprint is the thumbprint of the verified TLS client certificate, if any
+	CertThumbprint string
+}
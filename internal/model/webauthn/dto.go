@@ -0,0 +1,94 @@
+package webauthn
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// ----------------------------------------------------
+
+// CeremonyResponse carries the options to pass to navigator.credentials.create/get
+// and the session id to send back when finishing the ceremony.
+type CeremonyResponse struct {
+	SessionID uuid.UUID `json:"sessionId"`
+	Options   any       `json:"options"`
+}
+
+// ----------------------------------------------------
+
+type FinishRegistrationPayload struct {
+	SessionID  uuid.UUID       `json:"sessionId" validate:"required"`
+	Name       *string         `json:"name,omitempty" validate:"omitempty,max=100"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
+
+func (p *FinishRegistrationPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ----------------------------------------------------
+
+type FinishLoginPayload struct {
+	SessionID  uuid.UUID       `json:"sessionId" validate:"required"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
+
+func (p *FinishLoginPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ----------------------------------------------------
+
+type BeginMFAPayload struct {
+	MFAToken string `json:"mfaToken" validate:"required"`
+}
+
+func (p *BeginMFAPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ----------------------------------------------------
+
+type FinishMFAPayload struct {
+	MFAToken   string          `json:"mfaToken" validate:"required"`
+	SessionID  uuid.UUID       `json:"sessionId" validate:"required"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
+
+func (p *FinishMFAPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ----------------------------------------------------
+
+type RenameCredentialPayload struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+func (p *RenameCredentialPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ----------------------------------------------------
+
+type CredentialResponse struct {
+	ID             uuid.UUID  `json:"id"`
+	Name           *string    `json:"name"`
+	Transports     []string   `json:"transports"`
+	SignCount      int64      `json:"signCount"`
+	CloneWarning   bool       `json:"cloneWarning"`
+	BackupEligible bool       `json:"backupEligible"`
+	BackupState    bool       `json:"backupState"`
+	LastUsedAt     *time.Time `json:"lastUsedAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+// ----------------------------------------------------
@@ -0,0 +1,44 @@
+package webauthn
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/2SSK/jwt/internal/model"
+	"github.com/google/uuid"
+)
+
+const (
+	CeremonyRegistration = "registration"
+	CeremonyLogin        = "login"
+	CeremonyMFA          = "mfa"
+	CeremonyReauth       = "reauthentication"
+)
+
+// Credential is a registered passkey or security key
+type Credential struct {
+	model.Base
+	UserID          uuid.UUID  `json:"userId" db:"user_id"`
+	CredentialID    []byte     `json:"-" db:"credential_id"`
+	PublicKey       []byte     `json:"-" db:"public_key"`
+	AttestationType string     `json:"attestationType" db:"attestation_type"`
+	Transports      []string   `json:"transports" db:"transports"`
+	AAGUID          []byte     `json:"-" db:"aaguid"`
+	SignCount       int64      `json:"signCount" db:"sign_count"`
+	CloneWarning    bool       `json:"cloneWarning" db:"clone_warning"`
+	UserVerified    bool       `json:"userVerified" db:"user_verified"`
+	BackupEligible  bool       `json:"backupEligible" db:"backup_eligible"`
+	BackupState     bool       `json:"backupState" db:"backup_state"`
+	Name            *string    `json:"name" db:"name"`
+	LastUsedAt      *time.Time `json:"lastUsedAt" db:"last_used_at"`
+}
+
+// Session holds the server side state of an in-flight ceremony
+type Session struct {
+	model.BaseWithId
+	UserID    *uuid.UUID      `json:"userId" db:"user_id"`
+	Ceremony  string          `json:"ceremony" db:"ceremony"`
+	Data      json.RawMessage `json:"-" db:"data"`
+	ExpiresAt time.Time       `json:"expiresAt" db:"expires_at"`
+	model.BaseWithCreatedAt
+}
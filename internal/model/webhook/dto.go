@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// ----------------------------------------------------
+
+type CreateEndpointPayload struct {
+	URL         string   `json:"url" validate:"required,url,startswith=http"`
+	Description *string  `json:"description,omitempty" validate:"omitempty,max=255"`
+	EventTypes  []string `json:"eventTypes" validate:"required,min=1,dive,oneof=user.created user.updated user.deleted"`
+}
+
+func (p *CreateEndpointPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ----------------------------------------------------
+
+type UpdateEndpointPayload struct {
+	URL         *string  `json:"url,omitempty" validate:"omitempty,url,startswith=http"`
+	Description *string  `json:"description,omitempty" validate:"omitempty,max=255"`
+	EventTypes  []string `json:"eventTypes,omitempty" validate:"omitempty,min=1,dive,oneof=user.created user.updated user.deleted"`
+	Enabled     *bool    `json:"enabled,omitempty"`
+}
+
+func (p *UpdateEndpointPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ----------------------------------------------------
+
+type EndpointResponse struct {
+	ID                  uuid.UUID  `json:"id"`
+	URL                 string     `json:"url"`
+	Description         *string    `json:"description"`
+	EventTypes          []string   `json:"eventTypes"`
+	Enabled             bool       `json:"enabled"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	DisabledAt          *time.Time `json:"disabledAt"`
+	DisabledReason      *string    `json:"disabledReason"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	UpdatedAt           time.Time  `json:"updatedAt"`
+}
+
+// ----------------------------------------------------
+
+// CreateEndpointResponse is the only response that exposes the signing secret
+type CreateEndpointResponse struct {
+	EndpointResponse
+	Secret string `json:"secret"`
+}
+
+// ----------------------------------------------------
+
+type DeliveryResponse struct {
+	ID             uuid.UUID      `json:"id"`
+	EndpointID     uuid.UUID      `json:"endpointId"`
+	EventID        uuid.UUID      `json:"eventId"`
+	EventType      EventType      `json:"eventType"`
+	Status         DeliveryStatus `json:"status"`
+	Attempts       int            `json:"attempts"`
+	NextAttemptAt  *time.Time     `json:"nextAttemptAt"`
+	LastAttemptAt  *time.Time     `json:"lastAttemptAt"`
+	ResponseStatus *int           `json:"responseStatus"`
+	ResponseBody   *string        `json:"responseBody"`
+	Error          *string        `json:"error"`
+	DurationMs     *int           `json:"durationMs"`
+	RedeliveryOf   *uuid.UUID     `json:"redeliveryOf"`
+	CreatedAt      time.Time      `json:"createdAt"`
+}
+
+// ----------------------------------------------------
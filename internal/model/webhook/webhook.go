@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/2SSK/jwt/internal/model"
+	"github.com/google/uuid"
+)
+
+type EventType string
+
+const (
+	EventUserCreated EventType = "user.created"
+	EventUserUpdated EventType = "user.updated"
+	EventUserDeleted EventType = "user.deleted"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending   DeliveryStatus = "pending"
+	DeliveryStatusSucceeded DeliveryStatus = "succeeded"
+	DeliveryStatusFailed    DeliveryStatus = "failed"
+)
+
+type Endpoint struct {
+	model.Base
+	URL                 string     `json:"url" db:"url"`
+	Description         *string    `json:"description" db:"description"`
+	Secret              string     `json:"-" db:"secret"`
+	EventTypes          []string   `json:"eventTypes" db:"event_types"`
+	Enabled             bool       `json:"enabled" db:"enabled"`
+	ConsecutiveFailures int        `json:"consecutiveFailures" db:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabledAt" db:"disabled_at"`
+	DisabledReason      *string    `json:"disabledReason" db:"disabled_reason"`
+	CreatedBy           *uuid.UUID `json:"createdBy" db:"created_by"`
+}
+
+type Delivery struct {
+	model.Base
+	EndpointID     uuid.UUID       `json:"endpointId" db:"endpoint_id"`
+	EventID        uuid.UUID       `json:"eventId" db:"event_id"`
+	EventType      EventType       `json:"eventType" db:"event_type"`
+	Payload        json.RawMessage `json:"payload" db:"payload"`
+	Status         DeliveryStatus  `json:"status" db:"status"`
+	Attempts       int             `json:"attempts" db:"attempts"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt" db:"next_attempt_at"`
+	LastAttemptAt  *time.Time      `json:"lastAttemptAt" db:"last_attempt_at"`
+	ResponseStatus *int            `json:"responseStatus" db:"response_status"`
+	ResponseBody   *string         `json:"responseBody" db:"response_body"`
+	Error          *string         `json:"error" db:"error"`
+	DurationMs     *int            `json:"durationMs" db:"duration_ms"`
+	RedeliveryOf   *uuid.UUID      `json:"redeliveryOf" db:"redelivery_of"`
+}
+
+// Event is the envelope posted to webhook endpoints
+type Event struct {
+	ID        uuid.UUID `json:"id"`
+	Type      EventType `json:"type"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      any       `json:"data"`
+}
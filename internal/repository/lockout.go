@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/2SSK/jwt/internal/model/lockout"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type lockoutRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLockoutRepository(db *pgxpool.Pool) LockoutRepository {
+	return &lockoutRepository{db: db}
+}
+
+func (r *lockoutRepository) GetThrottle(ctx context.Context, scope, subject string) (*lockout.Throttle, error) {
+	query := `
+		SELECT scope, subject, failures, last_failure_at, locked_until
+		FROM login_throttles
+		WHERE scope = $1 AND subject = $2`
+
+	t := &lockout.Throttle{}
+	err := r.db.QueryRow(ctx, query, scope, subject).Scan(
+		&t.Scope, &t.Subject, &t.Failures, &t.LastFailureAt, &t.LockedUntil,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// RecordFailure counts a failed login. Counters whose last failure is older than
+// windowStart restart from one.
+func (r *lockoutRepository) RecordFailure(ctx context.Context, scope, subject string, windowStart time.Time) (*lockout.Throttle, error) {
+	query := `
+		INSERT INTO login_throttles (scope, subject, failures, last_failure_at)
+		VALUES ($1, $2, 1, NOW())
+		ON CONFLICT (scope, subject) DO UPDATE SET
+			failures = CASE WHEN login_throttles.last_failure_at < $3 THEN 1 ELSE login_throttles.failures + 1 END,
+			last_failure_at = NOW()
+		RETURNING scope, subject, failures, last_failure_at, locked_until`
+
+	t := &lockout.Throttle{}
+	err := r.db.QueryRow(ctx, query, scope, subject, windowStart).Scan(
+		&t.Scope, &t.Subject, &t.Failures, &t.LastFailureAt, &t.LockedUntil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (r *lockoutRepository) SetLockedUntil(ctx context.Context, scope, subject string, until time.Time) error {
+	query := `UPDATE login_throttles SET locked_until = $1 WHERE scope = $2 AND subject = $3`
+
+	_, err := r.db.Exec(ctx, query, until, scope, subject)
+
+	return err
+}
+
+// ResetThrottle forgets all failures, reporting whether there were any
+func (r *lockoutRepository) ResetThrottle(ctx context.Context, scope, subject string) (bool, error) {
+	query := `DELETE FROM login_throttles WHERE scope = $1 AND subject = $2`
+
+	tag, err := r.db.Exec(ctx, query, scope, subject)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/2SSK/jwt/internal/model/loginhistory"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type loginHistoryRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLoginHistoryRepository(db *pgxpool.Pool) LoginHistoryRepository {
+	return &loginHistoryRepository{db: db}
+}
+
+func (r *loginHistoryRepository) CreateEvent(ctx context.Context, e *loginhistory.Event) (*loginhistory.Event, error) {
+	query := `
+		INSERT INTO login_events (user_id, email, method, success, failure_reason, ip_address, network, user_agent, device_fingerprint)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRow(ctx, query,
+		e.UserID, e.Email, e.Method, e.Success, e.FailureReason, e.IPAddress, e.Network, e.UserAgent, e.DeviceFingerprint,
+	).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (r *loginHistoryRepository) GetEventsByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*loginhistory.Event, error) {
+	query := `
+		SELECT id, user_id, email, method, success, failure_reason, ip_address, network, user_agent, device_fingerprint, created_at
+		FROM login_events
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*loginhistory.Event
+	for rows.Next() {
+		e := &loginhistory.Event{}
+		err := rows.Scan(
+			&e.ID, &e.UserID, &e.Email, &e.Method, &e.Success, &e.FailureReason,
+			&e.IPAddress, &e.Network, &e.UserAgent, &e.DeviceFingerprint, &e.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// GetFamiliarity checks the user's previous successful logins for the device and network
+func (r *loginHistoryRepository) GetFamiliarity(ctx context.Context, userID uuid.UUID, deviceFingerprint, network string) (*loginhistory.Familiarity, error) {
+	query := `
+		SELECT
+			COUNT(*) > 0,
+			COALESCE(BOOL_OR(device_fingerprint = $2), FALSE),
+			COALESCE(BOOL_OR(network = $3), FALSE)
+		FROM login_events
+		WHERE user_id = $1 AND success`
+
+	f := &loginhistory.Familiarity{}
+	err := r.db.QueryRow(ctx, query, userID, deviceFingerprint, network).Scan(&f.HasHistory, &f.KnownDevice, &f.KnownNetwork)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
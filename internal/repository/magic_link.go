@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/2SSK/jwt/internal/model/magiclink"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type magicLinkRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMagicLinkRepository(db *pgxpool.Pool) MagicLinkRepository {
+	return &magicLinkRepository{db: db}
+}
+
+// CreateMagicLink stores a new link and retires any earlier unused links for the user,
+// so only the most recent email can be redeemed. It returns nil when maxPerHour links
+// were already created for the user in the last hour. Requests for the same user
+// are serialized by an advisory lock, so concurrent ones cannot exceed the cap.
+func (r *magicLinkRepository) CreateMagicLink(ctx context.Context, l *magiclink.MagicLink, maxPerHour int) (*magiclink.MagicLink, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended('magic_link:' || $1::text, 0))`, l.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var sent int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM magic_links
+		WHERE user_id = $1 AND created_at >= NOW() - INTERVAL '1 hour'`, l.UserID).Scan(&sent)
+	if err != nil {
+		return nil, err
+	}
+	if sent >= maxPerHour {
+		return nil, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE magic_links SET consumed_at = NOW()
+		WHERE user_id = $1 AND consumed_at IS NULL`, l.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO magic_links (user_id, token_hash, code_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	err = tx.QueryRow(ctx, query, l.UserID, l.TokenHash, l.CodeHash, l.ExpiresAt).Scan(&l.ID, &l.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (r *magicLinkRepository) GetMagicLinkByTokenHash(ctx context.Context, tokenHash string) (*magiclink.MagicLink, error) {
+	query := `
+		SELECT id, user_id, token_hash, code_hash, attempts, expires_at, consumed_at, created_at
+		FROM magic_links
+		WHERE token_hash = $1`
+
+	return r.scanMagicLink(r.db.QueryRow(ctx, query, tokenHash))
+}
+
+// GetActiveMagicLinkByUserID returns the user's unused, unexpired link, if any
+func (r *magicLinkRepository) GetActiveMagicLinkByUserID(ctx context.Context, userID uuid.UUID) (*magiclink.MagicLink, error) {
+	query := `
+		SELECT id, user_id, token_hash, code_hash, attempts, expires_at, consumed_at, created_at
+		FROM magic_links
+		WHERE user_id = $1 AND consumed_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+		LIMIT 1`
+
+	return r.scanMagicLink(r.db.QueryRow(ctx, query, userID))
+}
+
+// IncrementMagicLinkAttempts counts a code attempt against the link, reporting false
+// without counting it when the link is used up, expired or out of attempts. Checking
+// and counting in one statement keeps parallel guesses under the limit.
+func (r *magicLinkRepository) IncrementMagicLinkAttempts(ctx context.Context, id uuid.UUID, maxAttempts int) (bool, error) {
+	query := `
+		UPDATE magic_links SET attempts = attempts + 1
+		WHERE id = $1 AND attempts < $2 AND consumed_at IS NULL AND expires_at > NOW()`
+
+	tag, err := r.db.Exec(ctx, query, id, maxAttempts)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// ConsumeMagicLink marks the link used, reporting false if it was already consumed
+func (r *magicLinkRepository) ConsumeMagicLink(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `UPDATE magic_links SET consumed_at = NOW() WHERE id = $1 AND consumed_at IS NULL`
+
+	tag, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *magicLinkRepository) scanMagicLink(row pgx.Row) (*magiclink.MagicLink, error) {
+	l := &magiclink.MagicLink{}
+	err := row.Scan(
+		&l.ID, &l.UserID, &l.TokenHash, &l.CodeHash, &l.Attempts, &l.ExpiresAt, &l.ConsumedAt, &l.CreatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return l, nil
+}
@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/2SSK/jwt/internal/model/mfa"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type mfaRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMFARepository(db *pgxpool.Pool) MFARepository {
+	return &mfaRepository{db: db}
+}
+
+func (r *mfaRepository) GetTOTPByUserID(ctx context.Context, userID uuid.UUID) (*mfa.TOTP, error) {
+	query := `
+		SELECT user_id, secret, key_id, confirmed_at, last_used_step, created_at, updated_at
+		FROM user_totp
+		WHERE user_id = $1`
+
+	t := &mfa.TOTP{}
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&t.UserID, &t.Secret, &t.KeyID, &t.ConfirmedAt, &t.LastUsedStep, &t.CreatedAt, &t.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// UpsertPendingTOTP stores a fresh unconfirmed secret, replacing any earlier unconfirmed one
+func (r *mfaRepository) UpsertPendingTOTP(ctx context.Context, userID uuid.UUID, secret, keyID string) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret, key_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, key_id = EXCLUDED.key_id, confirmed_at = NULL, last_used_step = 0, updated_at = NOW()
+		WHERE user_totp.confirmed_at IS NULL`
+
+	_, err := r.db.Exec(ctx, query, userID, secret, keyID)
+
+	return err
+}
+
+// UpdateTOTPSecret replaces the encrypted secret with the same secret sealed under
+// another key. It only applies while oldSecret is stored, so a concurrent
+// re-enrollment is not overwritten.
+func (r *mfaRepository) UpdateTOTPSecret(ctx context.Context, userID uuid.UUID, oldSecret, secret, keyID string) error {
+	query := `
+		UPDATE user_totp
+		SET secret = $3, key_id = $4, updated_at = NOW()
+		WHERE user_id = $1 AND secret = $2`
+
+	_, err := r.db.Exec(ctx, query, userID, oldSecret, secret, keyID)
+
+	return err
+}
+
+func (r *mfaRepository) ConfirmTOTP(ctx context.Context, userID uuid.UUID, step int64) error {
+	query := `
+		UPDATE user_totp
+		SET confirmed_at = NOW(), last_used_step = $2, updated_at = NOW()
+		WHERE user_id = $1`
+
+	_, err := r.db.Exec(ctx, query, userID, step)
+
+	return err
+}
+
+// UseTOTPStep records step as consumed. It reports false if the step (or a later one)
+// was already used, which is how replayed codes are rejected.
+func (r *mfaRepository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	query := `
+		UPDATE user_totp
+		SET last_used_step = $2, updated_at = NOW()
+		WHERE user_id = $1 AND last_used_step < $2`
+
+	tag, err := r.db.Exec(ctx, query, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *mfaRepository) DeleteTOTP(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *mfaRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`,
+			userID, hash,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UseRecoveryCode marks a matching unused code as used, reporting whether one existed
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	query := `
+		UPDATE mfa_recovery_codes
+		SET used_at = NOW()
+		WHERE id = (
+			SELECT id FROM mfa_recovery_codes
+			WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+			LIMIT 1
+			FOR UPDATE
+		)`
+
+	tag, err := r.db.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *mfaRepository) CountUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+
+	var count int
+	err := r.db.QueryRow(ctx, query, userID).Scan(&count)
+
+	return count, err
+}
+
+func (r *mfaRepository) CreateChallenge(ctx context.Context, c *mfa.Challenge) (*mfa.Challenge, error) {
+	query := `
+		INSERT INTO mfa_challenges (user_id, first_factor, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRow(ctx, query, c.UserID, c.FirstFactor, c.TokenHash, c.ExpiresAt).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (r *mfaRepository) GetChallengeByTokenHash(ctx context.Context, tokenHash string) (*mfa.Challenge, error) {
+	query := `
+		SELECT id, user_id, first_factor, token_hash, attempts, expires_at, consumed_at, created_at
+		FROM mfa_challenges
+		WHERE token_hash = $1`
+
+	c := &mfa.Challenge{}
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+		&c.ID, &c.UserID, &c.FirstFactor, &c.TokenHash, &c.Attempts, &c.ExpiresAt, &c.ConsumedAt, &c.CreatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// IncrementChallengeAttempts counts a second factor attempt against the challenge,
+// reporting false without counting it when the challenge is used up, expired or out
+// of attempts. Checking and counting in one statement keeps parallel guesses under
+// the limit.
+func (r *mfaRepository) IncrementChallengeAttempts(ctx context.Context, id uuid.UUID, maxAttempts int) (bool, error) {
+	query := `
+		UPDATE mfa_challenges SET attempts = attempts + 1
+		WHERE id = $1 AND attempts < $2 AND consumed_at IS NULL AND expires_at > NOW()`
+
+	tag, err := r.db.Exec(ctx, query, id, maxAttempts)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// ConsumeChallenge marks the challenge used, reporting false if it was already consumed
+func (r *mfaRepository) ConsumeChallenge(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `UPDATE mfa_challenges SET consumed_at = NOW() WHERE id = $1 AND consumed_at IS NULL`
+
+	tag, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
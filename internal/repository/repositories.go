@@ -2,8 +2,17 @@ package repository
 
 import (
 	"context"
+	"time"
 
+	"github.com/2SSK/jwt/internal/model/lockout"
+	"github.com/2SSK/jwt/internal/model/loginhistory"
+	"github.com/2SSK/jwt/internal/model/magiclink"
+	"github.com/2SSK/jwt/internal/model/mfa"
+	"github.com/2SSK/jwt/internal/model/session"
+	"github.com/2SSK/jwt/internal/model/token"
 	"github.com/2SSK/jwt/internal/model/user"
+	"github.com/2SSK/jwt/internal/model/webauthn"
+	"github.com/2SSK/jwt/internal/model/webhook"
 	"github.com/2SSK/jwt/internal/server"
 	"github.com/google/uuid"
 )
@@ -12,17 +21,119 @@ type UserRepository interface {
 	CreateUser(ctx context.Context, user *user.User) (*user.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*user.User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (*user.User, error)
-	GetUsers(ctx context.Context, limit, offset int) ([]*user.User, error)
+	GetUserByIDFromReplica(ctx context.Context, id uuid.UUID) (*user.User, error)
+	GetUsersFromReplica(ctx context.Context, limit, offset int) ([]*user.User, error)
 	UpdateUser(ctx context.Context, user *user.User) error
+	UpdatePassword(ctx context.Context, id uuid.UUID, hashedPassword string) error
+	AddPasswordHistory(ctx context.Context, userID uuid.UUID, hashedPassword string, keep int) error
+	GetPasswordHistory(ctx context.Context, userID uuid.UUID, limit int) ([]string, error)
 	DeleteUser(ctx context.Context, id uuid.UUID) error
 }
 
+type WebhookRepository interface {
+	CreateEndpoint(ctx context.Context, endpoint *webhook.Endpoint) (*webhook.Endpoint, error)
+	GetEndpointByID(ctx context.Context, id uuid.UUID) (*webhook.Endpoint, error)
+	GetEndpoints(ctx context.Context, limit, offset int) ([]*webhook.Endpoint, error)
+	GetEnabledEndpointsForEvent(ctx context.Context, eventType webhook.EventType) ([]*webhook.Endpoint, error)
+	UpdateEndpoint(ctx context.Context, endpoint *webhook.Endpoint) error
+	DeleteEndpoint(ctx context.Context, id uuid.UUID) error
+	RecordEndpointSuccess(ctx context.Context, id uuid.UUID) error
+	RecordEndpointFailure(ctx context.Context, id uuid.UUID, threshold int, reason string) (bool, error)
+	CreateDelivery(ctx context.Context, delivery *webhook.Delivery) (*webhook.Delivery, error)
+	GetDeliveryByID(ctx context.Context, id uuid.UUID) (*webhook.Delivery, error)
+	GetDeliveriesByEndpoint(ctx context.Context, endpointID uuid.UUID, limit, offset int) ([]*webhook.Delivery, error)
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*webhook.Delivery, error)
+	UpdateDelivery(ctx context.Context, delivery *webhook.Delivery) error
+}
+
+type MFARepository interface {
+	GetTOTPByUserID(ctx context.Context, userID uuid.UUID) (*mfa.TOTP, error)
+	UpsertPendingTOTP(ctx context.Context, userID uuid.UUID, secret, keyID string) error
+	UpdateTOTPSecret(ctx context.Context, userID uuid.UUID, oldSecret, secret, keyID string) error
+	ConfirmTOTP(ctx context.Context, userID uuid.UUID, step int64) error
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	DeleteTOTP(ctx context.Context, userID uuid.UUID) error
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+	CountUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error)
+	CreateChallenge(ctx context.Context, challenge *mfa.Challenge) (*mfa.Challenge, error)
+	GetChallengeByTokenHash(ctx context.Context, tokenHash string) (*mfa.Challenge, error)
+	IncrementChallengeAttempts(ctx context.Context, id uuid.UUID, maxAttempts int) (bool, error)
+	ConsumeChallenge(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+type WebAuthnRepository interface {
+	CreateCredential(ctx context.Context, credential *webauthn.Credential) (*webauthn.Credential, error)
+	GetCredentialsByUserID(ctx context.Context, userID uuid.UUID) ([]*webauthn.Credential, error)
+	CountCredentialsByUserID(ctx context.Context, userID uuid.UUID) (int, error)
+	RecordCredentialUse(ctx context.Context, credential *webauthn.Credential) error
+	RenameCredential(ctx context.Context, userID, id uuid.UUID, name string) (*webauthn.Credential, error)
+	DeleteCredential(ctx context.Context, userID, id uuid.UUID) (bool, error)
+	CreateSession(ctx context.Context, session *webauthn.Session) (*webauthn.Session, error)
+	ConsumeSession(ctx context.Context, id uuid.UUID, ceremony string) (*webauthn.Session, error)
+}
+
+type MagicLinkRepository interface {
+	CreateMagicLink(ctx context.Context, link *magiclink.MagicLink, maxPerHour int) (*magiclink.MagicLink, error)
+	GetMagicLinkByTokenHash(ctx context.Context, tokenHash string) (*magiclink.MagicLink, error)
+	GetActiveMagicLinkByUserID(ctx context.Context, userID uuid.UUID) (*magiclink.MagicLink, error)
+	IncrementMagicLinkAttempts(ctx context.Context, id uuid.UUID, maxAttempts int) (bool, error)
+	ConsumeMagicLink(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+type LockoutRepository interface {
+	GetThrottle(ctx context.Context, scope, subject string) (*lockout.Throttle, error)
+	RecordFailure(ctx context.Context, scope, subject string, windowStart time.Time) (*lockout.Throttle, error)
+	SetLockedUntil(ctx context.Context, scope, subject string, until time.Time) error
+	ResetThrottle(ctx context.Context, scope, subject string) (bool, error)
+}
+
+type LoginHistoryRepository interface {
+	CreateEvent(ctx context.Context, event *loginhistory.Event) (*loginhistory.Event, error)
+	GetEventsByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*loginhistory.Event, error)
+	GetFamiliarity(ctx context.Context, userID uuid.UUID, deviceFingerprint, network string) (*loginhistory.Familiarity, error)
+}
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *session.Session) (*session.Session, error)
+	GetSessionByID(ctx context.Context, id uuid.UUID) (*session.Session, error)
+	GetActiveSessionsByUserID(ctx context.Context, userID uuid.UUID) ([]*session.Session, error)
+	TouchSession(ctx context.Context, id uuid.UUID, seenAt time.Time) error
+	ExtendSession(ctx context.Context, id uuid.UUID, expiresAt time.Time) (bool, error)
+	UpdateSessionAuthentication(ctx context.Context, id uuid.UUID, authTime time.Time, amr []string, acr int) (bool, error)
+	RevokeSession(ctx context.Context, userID, id uuid.UUID) (bool, error)
+	RevokeUserSessions(ctx context.Context, userID uuid.UUID, except *uuid.UUID) (int, error)
+	RevokeOldestSessions(ctx context.Context, userID uuid.UUID, keep int) (int, error)
+}
+
+type TokenRepository interface {
+	CreateOpaqueToken(ctx context.Context, token *token.OpaqueToken) (*token.OpaqueToken, error)
+	GetOpaqueTokenByHash(ctx context.Context, tokenHash string) (*token.OpaqueToken, error)
+	DeleteExpiredOpaqueTokens(ctx context.Context) (int, error)
+}
+
 type Repositories struct {
-	User UserRepository
+	User         UserRepository
+	Webhook      WebhookRepository
+	MFA          MFARepository
+	WebAuthn     WebAuthnRepository
+	MagicLink    MagicLinkRepository
+	Lockout      LockoutRepository
+	LoginHistory LoginHistoryRepository
+	Session      SessionRepository
+	Token        TokenRepository
 }
 
 func NewRepositories(s *server.Server) *Repositories {
 	return &Repositories{
-		User: NewUserRepository(s.DB.Pool),
+		User:         NewUserRepository(s.DB.Pool, s.DB.Replica),
+		Webhook:      NewWebhookRepository(s.DB.Pool),
+		MFA:          NewMFARepository(s.DB.Pool),
+		WebAuthn:     NewWebAuthnRepository(s.DB.Pool),
+		MagicLink:    NewMagicLinkRepository(s.DB.Pool),
+		Lockout:      NewLockoutRepository(s.DB.Pool),
+		LoginHistory: NewLoginHistoryRepository(s.DB.Pool),
+		Session:      NewSessionRepository(s.DB.Pool),
+		Token:        NewTokenRepository(s.DB.Pool),
 	}
 }
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/2SSK/jwt/internal/model/session"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type sessionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSessionRepository(db *pgxpool.Pool) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) CreateSession(ctx context.Context, s *session.Session) (*session.Session, error) {
+	query := `
+		INSERT INTO sessions (user_id, client_id, device_name, ip_address, user_agent, expires_at, absolute_expires_at, auth_time, amr, acr, dpop_jkt, cert_thumbprint)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, last_seen_at, created_at`
+
+	err := r.db.QueryRow(ctx, query,
+		s.UserID, s.ClientID, s.DeviceName, s.IPAddress, s.UserAgent, s.ExpiresAt, s.AbsoluteExpiresAt, s.AuthTime, s.AMR, s.ACR, s.DPoPJKT, s.CertThumbprint,
+	).Scan(&s.ID, &s.LastSeenAt, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (r *sessionRepository) GetSessionByID(ctx context.Context, id uuid.UUID) (*session.Session, error) {
+	query := `
+		SELECT id, user_id, client_id, device_name, ip_address, user_agent, last_seen_at, expires_at, absolute_expires_at,
+			auth_time, amr, acr, dpop_jkt, cert_thumbprint, revoked_at, created_at
+		FROM sessions
+		WHERE id = $1`
+
+	s := &session.Session{}
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&s.ID, &s.UserID, &s.ClientID, &s.DeviceName, &s.IPAddress, &s.UserAgent,
+		&s.LastSeenAt, &s.ExpiresAt, &s.AbsoluteExpiresAt, &s.AuthTime, &s.AMR, &s.ACR, &s.DPoPJKT, &s.CertThumbprint, &s.RevokedAt, &s.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// GetActiveSessionsByUserID lists the sessions that are neither revoked nor expired, most recently used first
+func (r *sessionRepository) GetActiveSessionsByUserID(ctx context.Context, userID uuid.UUID) ([]*session.Session, error) {
+	query := `
+		SELECT id, user_id, client_id, device_name, ip_address, user_agent, last_seen_at, expires_at, absolute_expires_at,
+			auth_time, amr, acr, dpop_jkt, cert_thumbprint, revoked_at, created_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW() AND absolute_expires_at > NOW()
+		ORDER BY last_seen_at DESC`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*session.Session
+	for rows.Next() {
+		s := &session.Session{}
+		err := rows.Scan(
+			&s.ID, &s.UserID, &s.ClientID, &s.DeviceName, &s.IPAddress, &s.UserAgent,
+			&s.LastSeenAt, &s.ExpiresAt, &s.AbsoluteExpiresAt, &s.AuthTime, &s.AMR, &s.ACR, &s.DPoPJKT, &s.CertThumbprint, &s.RevokedAt, &s.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+
+	return sessions, rows.Err()
+}
+
+func (r *sessionRepository) TouchSession(ctx context.Context, id uuid.UUID, seenAt time.Time) error {
+	_, err := r.db.Exec(ctx, `UPDATE sessions SET last_seen_at = $2 WHERE id = $1 AND revoked_at IS NULL`, id, seenAt)
+	return err
+}
+
+// ExtendSession slides the idle expiry of an active session, reporting false when
+// it has been revoked or has already expired
+func (r *sessionRepository) ExtendSession(ctx context.Context, id uuid.UUID, expiresAt time.Time) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE sessions SET expires_at = $2, last_seen_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL AND expires_at > NOW() AND absolute_expires_at > NOW()`, id, expiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// UpdateSessionAuthentication records a re-authentication on an active session,
+// reporting false when it has been revoked or has expired
+func (r *sessionRepository) UpdateSessionAuthentication(ctx context.Context, id uuid.UUID, authTime time.Time, amr []string, acr int) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE sessions SET auth_time = $2, amr = $3, acr = $4, last_seen_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL AND expires_at > NOW() AND absolute_expires_at > NOW()`, id, authTime, amr, acr)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// RevokeSession reports false when the user has no active session with that id
+func (r *sessionRepository) RevokeSession(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`, id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// RevokeUserSessions revokes all of the user's active sessions except the one given, if any
+func (r *sessionRepository) RevokeUserSessions(ctx context.Context, userID uuid.UUID, except *uuid.UUID) (int, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL AND ($2::uuid IS NULL OR id <> $2)`, userID, except)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+// RevokeOldestSessions keeps the user's newest keep active sessions and revokes the rest
+func (r *sessionRepository) RevokeOldestSessions(ctx context.Context, userID uuid.UUID, keep int) (int, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE id IN (
+			SELECT id FROM sessions
+			WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW() AND absolute_expires_at > NOW()
+			ORDER BY created_at DESC
+			OFFSET $2
+		)`, userID, keep)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(tag.RowsAffected()), nil
+}
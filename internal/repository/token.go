@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/2SSK/jwt/internal/model/token"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type tokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTokenRepository(db *pgxpool.Pool) TokenRepository {
+	return &tokenRepository{db: db}
+}
+
+func (r *tokenRepository) CreateOpaqueToken(ctx context.Context, t *token.OpaqueToken) (*token.OpaqueToken, error) {
+	query := `
+		INSERT INTO opaque_tokens (token_hash, token_type, user_id, session_id, claims, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRow(ctx, query,
+		t.TokenHash, t.TokenType, t.UserID, t.SessionID, t.Claims, t.ExpiresAt,
+	).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (r *tokenRepository) GetOpaqueTokenByHash(ctx context.Context, tokenHash string) (*token.OpaqueToken, error) {
+	query := `
+		SELECT id, token_hash, token_type, user_id, session_id, claims, expires_at, created_at
+		FROM opaque_tokens
+		WHERE token_hash = $1`
+
+	t := &token.OpaqueToken{}
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+		&t.ID, &t.TokenHash, &t.TokenType, &t.UserID, &t.SessionID, &t.Claims, &t.ExpiresAt, &t.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// DeleteExpiredOpaqueTokens removes tokens past their expiry and returns how many were deleted
+func (r *tokenRepository) DeleteExpiredOpaqueTokens(ctx context.Context) (int, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM opaque_tokens WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(tag.RowsAffected()), nil
+}
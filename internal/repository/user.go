@@ -10,11 +10,25 @@ import (
 )
 
 type userRepository struct {
-	db *pgxpool.Pool
+	db      *pgxpool.Pool
+	replica func() *pgxpool.Pool
 }
 
-func NewUserRepository(db *pgxpool.Pool) UserRepository {
-	return &userRepository{db: db}
+// NewUserRepository sends the FromReplica reads to the pool replica returns, when
+// it returns one. Everything else stays on the primary.
+func NewUserRepository(db *pgxpool.Pool, replica func() *pgxpool.Pool) UserRepository {
+	return &userRepository{db: db, replica: replica}
+}
+
+// read runs a read on a replica, and again on the primary when the replica fails
+// or has not caught up with the row yet
+func (r *userRepository) read(ctx context.Context, query func(db *pgxpool.Pool) error) error {
+	if replica := r.replica(); replica != nil {
+		if err := query(replica); err == nil || ctx.Err() != nil {
+			return err
+		}
+	}
+	return query(r.db)
 }
 
 func (r *userRepository) CreateUser(ctx context.Context, u *user.User) (*user.User, error) {
@@ -56,13 +70,37 @@ func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*use
 }
 
 func (r *userRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	return r.getUserByID(ctx, r.db, id)
+}
+
+// GetUserByIDFromReplica may return a row as it was a moment ago. It is only for
+// displaying users, never for authorization, authentication or read-modify-write.
+func (r *userRepository) GetUserByIDFromReplica(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	var u *user.User
+	err := r.read(ctx, func(db *pgxpool.Pool) error {
+		var err error
+		u, err = r.getUserByID(ctx, db, id)
+		if err == nil && u == nil {
+			// Possibly not replicated yet, look on the primary
+			return pgx.ErrNoRows
+		}
+		return err
+	})
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+
+	return u, err
+}
+
+func (r *userRepository) getUserByID(ctx context.Context, db *pgxpool.Pool, id uuid.UUID) (*user.User, error) {
 	query := `
 		SELECT id, first_name, last_name, password, email, phone, user_type, created_at, updated_at
 		FROM users
 		WHERE id = $1`
 
 	u := &user.User{}
-	err := r.db.QueryRow(ctx, query, id).Scan(
+	err := db.QueryRow(ctx, query, id).Scan(
 		&u.ID, &u.FirstName, &u.LastName, &u.Password, &u.Email, &u.Phone, &u.UserType, &u.CreatedAt, &u.UpdatedAt,
 	)
 
@@ -76,29 +114,38 @@ func (r *userRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*user.U
 	return u, nil
 }
 
-func (r *userRepository) GetUsers(ctx context.Context, limit, offset int) ([]*user.User, error) {
+// GetUsersFromReplica lists users for display, possibly a moment out of date
+func (r *userRepository) GetUsersFromReplica(ctx context.Context, limit, offset int) ([]*user.User, error) {
 	query := `
 		SELECT id, first_name, last_name, password, email, phone, user_type, created_at, updated_at
 		FROM users
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2`
 
-	rows, err := r.db.Query(ctx, query, limit, offset)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
 	var users []*user.User
-	for rows.Next() {
-		u := &user.User{}
-		err := rows.Scan(
-			&u.ID, &u.FirstName, &u.LastName, &u.Password, &u.Email, &u.Phone, &u.UserType, &u.CreatedAt, &u.UpdatedAt,
-		)
+	err := r.read(ctx, func(db *pgxpool.Pool) error {
+		rows, err := db.Query(ctx, query, limit, offset)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		users = append(users, u)
+		defer rows.Close()
+
+		users = nil
+		for rows.Next() {
+			u := &user.User{}
+			err := rows.Scan(
+				&u.ID, &u.FirstName, &u.LastName, &u.Password, &u.Email, &u.Phone, &u.UserType, &u.CreatedAt, &u.UpdatedAt,
+			)
+			if err != nil {
+				return err
+			}
+			users = append(users, u)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return users, nil
@@ -117,6 +164,65 @@ func (r *userRepository) UpdateUser(ctx context.Context, u *user.User) error {
 	return err
 }
 
+func (r *userRepository) UpdatePassword(ctx context.Context, id uuid.UUID, hashedPassword string) error {
+	query := `UPDATE users SET password = $1, updated_at = NOW() WHERE id = $2`
+
+	_, err := r.db.Exec(ctx, query, hashedPassword, id)
+
+	return err
+}
+
+// AddPasswordHistory records a newly set password hash and keeps only the latest keep entries
+func (r *userRepository) AddPasswordHistory(ctx context.Context, userID uuid.UUID, hashedPassword string, keep int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `INSERT INTO password_history (user_id, password_hash) VALUES ($1, $2)`, userID, hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM password_history
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM password_history WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2
+		)`, userID, keep)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *userRepository) GetPasswordHistory(ctx context.Context, userID uuid.UUID, limit int) ([]string, error) {
+	query := `
+		SELECT password_hash
+		FROM password_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+
+	return hashes, rows.Err()
+}
+
 func (r *userRepository) DeleteUser(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM users WHERE id = $1`
 
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/2SSK/jwt/internal/model/webauthn"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type webauthnRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebAuthnRepository(db *pgxpool.Pool) WebAuthnRepository {
+	return &webauthnRepository{db: db}
+}
+
+const credentialColumns = `id, user_id, credential_id, public_key, attestation_type, transports, aaguid, sign_count,
+		clone_warning, user_verified, backup_eligible, backup_state, name, last_used_at, created_at, updated_at`
+
+func scanCredential(row pgx.Row) (*webauthn.Credential, error) {
+	c := &webauthn.Credential{}
+	err := row.Scan(
+		&c.ID, &c.UserID, &c.CredentialID, &c.PublicKey, &c.AttestationType, &c.Transports, &c.AAGUID, &c.SignCount,
+		&c.CloneWarning, &c.UserVerified, &c.BackupEligible, &c.BackupState, &c.Name, &c.LastUsedAt, &c.CreatedAt, &c.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *webauthnRepository) CreateCredential(ctx context.Context, c *webauthn.Credential) (*webauthn.Credential, error) {
+	query := `
+		INSERT INTO webauthn_credentials (user_id, credential_id, public_key, attestation_type, transports, aaguid,
+			sign_count, user_verified, backup_eligible, backup_state, name)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(ctx, query,
+		c.UserID, c.CredentialID, c.PublicKey, c.AttestationType, c.Transports, c.AAGUID,
+		c.SignCount, c.UserVerified, c.BackupEligible, c.BackupState, c.Name,
+	).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (r *webauthnRepository) GetCredentialsByUserID(ctx context.Context, userID uuid.UUID) ([]*webauthn.Credential, error) {
+	query := `SELECT ` + credentialColumns + `
+		FROM webauthn_credentials
+		WHERE user_id = $1
+		ORDER BY created_at`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var credentials []*webauthn.Credential
+	for rows.Next() {
+		c, err := scanCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, c)
+	}
+
+	return credentials, rows.Err()
+}
+
+func (r *webauthnRepository) CountCredentialsByUserID(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM webauthn_credentials WHERE user_id = $1`
+
+	var count int
+	err := r.db.QueryRow(ctx, query, userID).Scan(&count)
+
+	return count, err
+}
+
+// RecordCredentialUse stores the authenticator state reported by a successful assertion
+func (r *webauthnRepository) RecordCredentialUse(ctx context.Context, c *webauthn.Credential) error {
+	query := `
+		UPDATE webauthn_credentials
+		SET sign_count = $1, clone_warning = $2, backup_state = $3, last_used_at = NOW(), updated_at = NOW()
+		WHERE id = $4`
+
+	_, err := r.db.Exec(ctx, query, c.SignCount, c.CloneWarning, c.BackupState, c.ID)
+
+	return err
+}
+
+func (r *webauthnRepository) RenameCredential(ctx context.Context, userID, id uuid.UUID, name string) (*webauthn.Credential, error) {
+	query := `
+		UPDATE webauthn_credentials
+		SET name = $1, updated_at = NOW()
+		WHERE id = $2 AND user_id = $3
+		RETURNING ` + credentialColumns
+
+	c, err := scanCredential(r.db.QueryRow(ctx, query, name, id, userID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// DeleteCredential removes one of the user's credentials, reporting whether it existed
+func (r *webauthnRepository) DeleteCredential(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	query := `DELETE FROM webauthn_credentials WHERE id = $1 AND user_id = $2`
+
+	tag, err := r.db.Exec(ctx, query, id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *webauthnRepository) CreateSession(ctx context.Context, s *webauthn.Session) (*webauthn.Session, error) {
+	query := `
+		INSERT INTO webauthn_sessions (user_id, ceremony, data, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRow(ctx, query, s.UserID, s.Ceremony, s.Data, s.ExpiresAt).Scan(&s.ID, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// ConsumeSession deletes and returns a session so that each ceremony can only be finished once
+func (r *webauthnRepository) ConsumeSession(ctx context.Context, id uuid.UUID, ceremony string) (*webauthn.Session, error) {
+	query := `
+		DELETE FROM webauthn_sessions
+		WHERE id = $1 AND ceremony = $2
+		RETURNING id, user_id, ceremony, data, expires_at, created_at`
+
+	s := &webauthn.Session{}
+	err := r.db.QueryRow(ctx, query, id, ceremony).Scan(
+		&s.ID, &s.UserID, &s.Ceremony, &s.Data, &s.ExpiresAt, &s.CreatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return s, nil
+}
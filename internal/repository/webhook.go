@@ -0,0 +1,291 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/2SSK/jwt/internal/model/webhook"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type webhookRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookRepository(db *pgxpool.Pool) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+const endpointColumns = `id, url, description, secret, event_types, enabled, consecutive_failures,
+		disabled_at, disabled_reason, created_by, created_at, updated_at`
+
+const deliveryColumns = `id, endpoint_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+		last_attempt_at, response_status, response_body, error, duration_ms, redelivery_of, created_at, updated_at`
+
+func scanEndpoint(row pgx.Row) (*webhook.Endpoint, error) {
+	e := &webhook.Endpoint{}
+	err := row.Scan(
+		&e.ID, &e.URL, &e.Description, &e.Secret, &e.EventTypes, &e.Enabled, &e.ConsecutiveFailures,
+		&e.DisabledAt, &e.DisabledReason, &e.CreatedBy, &e.CreatedAt, &e.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func scanDelivery(row pgx.Row) (*webhook.Delivery, error) {
+	d := &webhook.Delivery{}
+	err := row.Scan(
+		&d.ID, &d.EndpointID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastAttemptAt, &d.ResponseStatus, &d.ResponseBody, &d.Error, &d.DurationMs, &d.RedeliveryOf,
+		&d.CreatedAt, &d.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *webhookRepository) CreateEndpoint(ctx context.Context, e *webhook.Endpoint) (*webhook.Endpoint, error) {
+	query := `
+		INSERT INTO webhook_endpoints (url, description, secret, event_types, enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(ctx, query,
+		e.URL, e.Description, e.Secret, e.EventTypes, e.Enabled, e.CreatedBy,
+	).Scan(&e.ID, &e.CreatedAt, &e.UpdatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (r *webhookRepository) GetEndpointByID(ctx context.Context, id uuid.UUID) (*webhook.Endpoint, error) {
+	query := `SELECT ` + endpointColumns + ` FROM webhook_endpoints WHERE id = $1`
+
+	e, err := scanEndpoint(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (r *webhookRepository) GetEndpoints(ctx context.Context, limit, offset int) ([]*webhook.Endpoint, error) {
+	query := `SELECT ` + endpointColumns + `
+		FROM webhook_endpoints
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2`
+
+	rows, err := r.db.Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var endpoints []*webhook.Endpoint
+	for rows.Next() {
+		e, err := scanEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, e)
+	}
+
+	return endpoints, rows.Err()
+}
+
+func (r *webhookRepository) GetEnabledEndpointsForEvent(ctx context.Context, eventType webhook.EventType) ([]*webhook.Endpoint, error) {
+	query := `SELECT ` + endpointColumns + `
+		FROM webhook_endpoints
+		WHERE enabled = TRUE AND $1 = ANY(event_types)`
+
+	rows, err := r.db.Query(ctx, query, string(eventType))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var endpoints []*webhook.Endpoint
+	for rows.Next() {
+		e, err := scanEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, e)
+	}
+
+	return endpoints, rows.Err()
+}
+
+func (r *webhookRepository) UpdateEndpoint(ctx context.Context, e *webhook.Endpoint) error {
+	query := `
+		UPDATE webhook_endpoints
+		SET url = $1, description = $2, event_types = $3, enabled = $4, consecutive_failures = $5,
+			disabled_at = $6, disabled_reason = $7, updated_at = NOW()
+		WHERE id = $8`
+
+	_, err := r.db.Exec(ctx, query,
+		e.URL, e.Description, e.EventTypes, e.Enabled, e.ConsecutiveFailures,
+		e.DisabledAt, e.DisabledReason, e.ID,
+	)
+
+	return err
+}
+
+func (r *webhookRepository) DeleteEndpoint(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM webhook_endpoints WHERE id = $1`
+
+	_, err := r.db.Exec(ctx, query, id)
+
+	return err
+}
+
+func (r *webhookRepository) RecordEndpointSuccess(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE webhook_endpoints
+		SET consecutive_failures = 0, updated_at = NOW()
+		WHERE id = $1 AND consecutive_failures <> 0`
+
+	_, err := r.db.Exec(ctx, query, id)
+
+	return err
+}
+
+// RecordEndpointFailure bumps the failure counter and disables the endpoint once it
+// reaches threshold. It reports whether this call is the one that disabled it.
+func (r *webhookRepository) RecordEndpointFailure(ctx context.Context, id uuid.UUID, threshold int, reason string) (bool, error) {
+	query := `
+		UPDATE webhook_endpoints
+		SET consecutive_failures = consecutive_failures + 1,
+			enabled = CASE WHEN consecutive_failures + 1 >= $2 THEN FALSE ELSE enabled END,
+			disabled_at = CASE WHEN enabled AND consecutive_failures + 1 >= $2 THEN NOW() ELSE disabled_at END,
+			disabled_reason = CASE WHEN enabled AND consecutive_failures + 1 >= $2 THEN $3 ELSE disabled_reason END,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING disabled_at IS NOT NULL AND disabled_at >= NOW()`
+
+	var disabledNow bool
+	err := r.db.QueryRow(ctx, query, id, threshold, reason).Scan(&disabledNow)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return disabledNow, nil
+}
+
+func (r *webhookRepository) CreateDelivery(ctx context.Context, d *webhook.Delivery) (*webhook.Delivery, error) {
+	query := `
+		INSERT INTO webhook_deliveries (endpoint_id, event_id, event_type, payload, status, next_attempt_at, redelivery_of)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, attempts, created_at, updated_at`
+
+	err := r.db.QueryRow(ctx, query,
+		d.EndpointID, d.EventID, d.EventType, d.Payload, d.Status, d.NextAttemptAt, d.RedeliveryOf,
+	).Scan(&d.ID, &d.Attempts, &d.CreatedAt, &d.UpdatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (r *webhookRepository) GetDeliveryByID(ctx context.Context, id uuid.UUID) (*webhook.Delivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+
+	d, err := scanDelivery(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (r *webhookRepository) GetDeliveriesByEndpoint(ctx context.Context, endpointID uuid.UUID, limit, offset int) ([]*webhook.Delivery, error) {
+	query := `SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries
+		WHERE endpoint_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(ctx, query, endpointID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*webhook.Delivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// ClaimDueDeliveries leases up to limit pending deliveries whose next attempt is due.
+// The lease pushes next_attempt_at forward so other replicas skip them while in flight.
+func (r *webhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*webhook.Delivery, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond', updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + deliveryColumns
+
+	rows, err := r.db.Query(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*webhook.Delivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func (r *webhookRepository) UpdateDelivery(ctx context.Context, d *webhook.Delivery) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = $2, next_attempt_at = $3, last_attempt_at = $4, response_status = $5,
+			response_body = $6, error = $7, duration_ms = $8, updated_at = NOW()
+		WHERE id = $9`
+
+	_, err := r.db.Exec(ctx, query,
+		d.Status, d.Attempts, d.NextAttemptAt, d.LastAttemptAt, d.ResponseStatus,
+		d.ResponseBody, d.Error, d.DurationMs, d.ID,
+	)
+
+	return err
+}
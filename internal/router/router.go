@@ -1,6 +1,7 @@
 package router
 
 import (
+	"net"
 	"net/http"
 
 	"github.com/2SSK/jwt/internal/handler"
@@ -19,9 +20,12 @@ func NewRouter(s *server.Server, h *handler.Handlers, services *service.Services
 	router := echo.New()
 
 	router.HTTPErrorHandler = middlewares.Global.GlobalErrorHandler
+	router.IPExtractor = ipExtractor(s.Config.Server.TrustedProxies)
 
 	// global middlewares
 	router.Use(
+		middlewares.Tracing.Trace(),
+		middlewares.Metrics.RecordRequests(),
 		echoMiddleware.RateLimiterWithConfig(echoMiddleware.RateLimiterConfig{
 			Store: echoMiddleware.NewRateLimiterMemoryStore(rate.Limit(20)),
 			DenyHandler: func(c echo.Context, identifier string, err error) error {
@@ -50,12 +54,34 @@ func NewRouter(s *server.Server, h *handler.Handlers, services *service.Services
 	)
 
 	// register system routes
-	registerSystemRoutes(router, h)
+	registerSystemRoutes(router, s, h)
 
 	// register versioned routes
-	v1Router := router.Group("/api/v1")
+	v1Router := router.Group("/api/v1", middlewares.Tracing.TraceHandler())
 
 	v1.RegisterV1Routes(v1Router, h, middlewares)
 
 	return router
 }
+
+// ipExtractor decides where c.RealIP() comes from. The lockout, login history and
+// rate limiter all key on it, so forwarding headers are only believed from the
+// configured proxies.
+func ipExtractor(trustedProxies []string) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, cidr := range trustedProxies {
+		// Validated by the config
+		_, ipNet, _ := net.ParseCIDR(cidr)
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+
+	return echo.ExtractIPFromXFFHeader(options...)
+}
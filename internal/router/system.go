@@ -2,11 +2,12 @@ package router
 
 import (
 	"github.com/2SSK/jwt/internal/handler"
+	"github.com/2SSK/jwt/internal/server"
 
 	"github.com/labstack/echo/v4"
 )
 
-func registerSystemRoutes(r *echo.Echo, h *handler.Handlers) {
+func registerSystemRoutes(r *echo.Echo, s *server.Server, h *handler.Handlers) {
 	r.GET("/", h.Home.ServeHome)
 
 	r.GET("/status", h.Health.CheckHealth)
@@ -14,4 +15,11 @@ func registerSystemRoutes(r *echo.Echo, h *handler.Handlers) {
 	r.Static("/static", "static")
 
 	r.GET("/docs", h.OpenAPI.ServeOpenAPIUI)
+
+	r.GET("/.well-known/jwks.json", h.Token.JWKS)
+
+	// With an admin port configured, metrics are served there instead
+	if metricsCfg := s.Config.Observability.Metrics; metricsCfg.Enabled && metricsCfg.Port == "" {
+		r.GET("/metrics", echo.WrapHandler(s.Metrics.Handler()))
+	}
 }
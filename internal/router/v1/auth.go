@@ -10,8 +10,49 @@ func registerAuthRoutes(r *echo.Group, handlers *handler.Handlers, authMiddlewar
 	// Auth routes
 	auth := r.Group("/auth")
 
+	// Routes that start a session authenticate tls_client_auth clients and bind
+	// the session to a DPoP key when a proof is sent
+	issuesTokens := []echo.MiddlewareFunc{authMiddleware.TLSClientAuth(), authMiddleware.DPoPProof()}
+
 	// Auth Operations
-	auth.POST("/signup", handlers.Auth.SignUp)                                      // User Signup
-	auth.POST("/login", handlers.Auth.Login)                                        // User Login
-	auth.POST("/refresh", handlers.Auth.RefreshToken, authMiddleware.RequireAuth()) // Refresh Token
+	auth.POST("/signup", handlers.Auth.SignUp, issuesTokens...)                                      // User Signup
+	auth.POST("/login", handlers.Auth.Login, issuesTokens...)                                        // User Login
+	auth.POST("/refresh", handlers.Auth.RefreshToken, authMiddleware.RequireRefreshToken())          // Refresh Token
+	auth.POST("/introspect", handlers.Token.Introspect, authMiddleware.RequireIntrospectionClient()) // Token Introspection
+	auth.POST("/logout", handlers.Auth.Logout, authMiddleware.RequireAuth())                         // Logout
+
+	// Re-authentication Operations
+	reauth := auth.Group("/reauthenticate", authMiddleware.RequireAuth())
+	reauth.POST("", handlers.Auth.Reauthenticate)                             // Re-authenticate With Password Or Code
+	reauth.POST("/webauthn/begin", handlers.WebAuthn.BeginReauthentication)   // Start Passkey Re-authentication
+	reauth.POST("/webauthn/finish", handlers.WebAuthn.FinishReauthentication) // Complete Passkey Re-authentication
+
+	// Magic Link Operations
+	auth.POST("/magic-link", handlers.MagicLink.Request)                        // Email Sign-In Link And Code
+	auth.POST("/magic-link/verify", handlers.MagicLink.Verify, issuesTokens...) // Redeem Sign-In Link Or Code
+
+	// MFA Operations
+	mfa := auth.Group("/mfa")
+	mfa.POST("/verify", handlers.MFA.Verify, issuesTokens...)                  // Complete MFA Login
+	mfa.POST("/webauthn/begin", handlers.WebAuthn.BeginMFA)                    // Start Passkey MFA
+	mfa.POST("/webauthn/finish", handlers.WebAuthn.FinishMFA, issuesTokens...) // Complete MFA Login With Passkey
+
+	mfaSettings := mfa.Group("", authMiddleware.RequireAuth())
+	mfaSettings.GET("", handlers.MFA.Status)                                                  // MFA Status
+	mfaSettings.POST("/totp/enroll", handlers.MFA.EnrollTOTP, authMiddleware.RequireStepUp()) // Start TOTP Enrollment
+	mfaSettings.POST("/totp/confirm", handlers.MFA.ConfirmTOTP)                               // Confirm TOTP Enrollment
+	mfaSettings.POST("/totp/disable", handlers.MFA.DisableTOTP)                               // Disable TOTP
+	mfaSettings.POST("/recovery-codes", handlers.MFA.RegenerateRecoveryCodes)                 // Regenerate Recovery Codes
+
+	// Passkey Operations
+	passkeys := auth.Group("/webauthn")
+	passkeys.POST("/login/begin", handlers.WebAuthn.BeginLogin)                    // Start Passkey Login
+	passkeys.POST("/login/finish", handlers.WebAuthn.FinishLogin, issuesTokens...) // Complete Passkey Login
+
+	passkeySettings := passkeys.Group("", authMiddleware.RequireAuth())
+	passkeySettings.POST("/register/begin", handlers.WebAuthn.BeginRegistration, authMiddleware.RequireStepUp())              // Start Passkey Registration
+	passkeySettings.POST("/register/finish", handlers.WebAuthn.FinishRegistration)                                            // Complete Passkey Registration
+	passkeySettings.GET("/credentials", handlers.WebAuthn.GetCredentials)                                                     // List Passkeys
+	passkeySettings.PUT("/credentials/:credential_id", handlers.WebAuthn.RenameCredential)                                    // Rename Passkey
+	passkeySettings.DELETE("/credentials/:credential_id", handlers.WebAuthn.DeleteCredential, authMiddleware.RequireStepUp()) // Delete Passkey
 }
@@ -0,0 +1,22 @@
+package v1
+
+import (
+	"github.com/2SSK/jwt/internal/handler"
+	"github.com/2SSK/jwt/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+func registerMeRoutes(r *echo.Group, auth *middleware.AuthMiddleware, handlers *handler.Handlers) {
+	// Current user routes
+	me := r.Group("/me")
+	me.Use(auth.RequireAuth()) // Authenticated users
+
+	// Account Operations
+	me.PUT("/password", handlers.User.ChangePassword)    // Change Password
+	me.GET("/logins", handlers.LoginHistory.GetMyLogins) // Login History
+
+	// Sessions
+	me.GET("/sessions", handlers.Session.GetMySessions)                  // List Active Sessions
+	me.DELETE("/sessions", handlers.Session.RevokeMyOtherSessions)       // Sign Out Other Sessions
+	me.DELETE("/sessions/:session_id", handlers.Session.RevokeMySession) // Revoke Session
+}
@@ -16,8 +16,14 @@ func registerUserRoutes(r *echo.Group, auth *middleware.AuthMiddleware, handlers
 
 	// Admin Operations
 	admin := r.Group("/user")
-	admin.Use(auth.RequireRole("admin"))                // Admin only
-	admin.GET("/:user_id", handlers.User.GetUserByID)   // Get User by ID
-	admin.PUT("/:user_id", handlers.User.UpdateUser)    // Update User
-	admin.DELETE("/:user_id", handlers.User.DeleteUser) // Delete User
+	admin.Use(auth.RequireRole("admin"))                                               // Admin only
+	admin.GET("/:user_id", handlers.User.GetUserByID)                                  // Get User by ID
+	admin.PUT("/:user_id", handlers.User.UpdateUser, auth.RequireStepUp())             // Update User
+	admin.DELETE("/:user_id", handlers.User.DeleteUser, auth.RequireStepUp())          // Delete User
+	admin.PUT("/:user_id/password", handlers.User.ResetPassword, auth.RequireStepUp()) // Reset User Password
+	admin.POST("/:user_id/unlock", handlers.User.UnlockUser)                           // Unlock User After Failed Logins
+	admin.GET("/:user_id/logins", handlers.LoginHistory.GetUserLogins)                 // User Login History
+	admin.GET("/:user_id/sessions", handlers.Session.GetUserSessions)                  // User Active Sessions
+	admin.DELETE("/:user_id/sessions", handlers.Session.RevokeUserSessions)            // Revoke All User Sessions
+	admin.DELETE("/:user_id/sessions/:session_id", handlers.Session.RevokeUserSession) // Revoke User Session
 }
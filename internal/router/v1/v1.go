@@ -12,4 +12,10 @@ func RegisterV1Routes(router *echo.Group, handlers *handler.Handlers, middleware
 
 	// User routes
 	registerUserRoutes(router, middleware.Auth, handlers)
+
+	// Current user routes
+	registerMeRoutes(router, middleware.Auth, handlers)
+
+	// Webhook routes
+	registerWebhookRoutes(router, middleware.Auth, handlers)
 }
@@ -0,0 +1,24 @@
+package v1
+
+import (
+	"github.com/2SSK/jwt/internal/handler"
+	"github.com/2SSK/jwt/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+func registerWebhookRoutes(r *echo.Group, auth *middleware.AuthMiddleware, handlers *handler.Handlers) {
+	// Webhook routes
+	webhooks := r.Group("/webhooks")
+	webhooks.Use(auth.RequireRole("admin")) // Admin only
+
+	// Endpoint Operations
+	webhooks.POST("", handlers.Webhook.CreateEndpoint)               // Register Endpoint
+	webhooks.GET("", handlers.Webhook.GetEndpoints)                  // List Endpoints
+	webhooks.GET("/:webhook_id", handlers.Webhook.GetEndpoint)       // Get Endpoint
+	webhooks.PUT("/:webhook_id", handlers.Webhook.UpdateEndpoint)    // Update Endpoint
+	webhooks.DELETE("/:webhook_id", handlers.Webhook.DeleteEndpoint) // Delete Endpoint
+
+	// Delivery Operations
+	webhooks.GET("/:webhook_id/deliveries", handlers.Webhook.GetDeliveries)                     // Delivery Log
+	webhooks.POST("/:webhook_id/deliveries/:delivery_id/redeliver", handlers.Webhook.Redeliver) // Redeliver
+}
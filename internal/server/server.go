@@ -2,13 +2,18 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/2SSK/jwt/internal/config"
 	"github.com/2SSK/jwt/internal/database"
+	"github.com/2SSK/jwt/internal/metrics"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog"
 )
 
@@ -16,25 +21,37 @@ type Server struct {
 	Config     *config.Config
 	Logger     *zerolog.Logger
 	DB         *database.Database
+	Metrics    *metrics.Metrics
 	httpServer *http.Server
+	// metricsServer serves /metrics on the admin port, when one is configured
+	metricsServer *http.Server
 }
 
 func New(cfg *config.Config, logger *zerolog.Logger) (*Server, error) {
-	db, err := database.New(cfg, logger)
+	m := metrics.New()
+
+	db, err := database.New(cfg, logger, m)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
+	pools := map[string]*pgxpool.Pool{"primary": db.Pool}
+	for i, replica := range db.Replicas {
+		pools[fmt.Sprintf("replica_%d", i)] = replica
+	}
+	m.RegisterPools(pools)
+
 	server := &Server{
-		Config: cfg,
-		Logger: logger,
-		DB:     db,
+		Config:  cfg,
+		Logger:  logger,
+		DB:      db,
+		Metrics: m,
 	}
 
 	return server, nil
 }
 
-func (s *Server) SetupHTTPServer(handler http.Handler) {
+func (s *Server) SetupHTTPServer(handler http.Handler) error {
 	s.httpServer = &http.Server{
 		Addr:         ":" + s.Config.Server.Port,
 		Handler:      handler,
@@ -42,6 +59,55 @@ func (s *Server) SetupHTTPServer(handler http.Handler) {
 		WriteTimeout: time.Duration(s.Config.Server.WriteTimeout) * time.Second,
 		IdleTimeout:  time.Duration(s.Config.Server.IdleTimeout) * time.Second,
 	}
+
+	if metricsCfg := s.Config.Observability.Metrics; metricsCfg.Enabled && metricsCfg.Port != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", s.Metrics.Handler())
+		s.metricsServer = &http.Server{
+			Addr:              ":" + metricsCfg.Port,
+			Handler:           mux,
+			ReadHeaderTimeout: time.Duration(s.Config.Server.ReadTimeout) * time.Second,
+		}
+	}
+
+	if !s.Config.TLS.Enabled {
+		return nil
+	}
+
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return err
+	}
+	s.httpServer.TLSConfig = tlsConfig
+
+	return nil
+}
+
+// tlsConfig verifies client certificates against the configured CAs, when set
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	cfg := s.Config.TLS
+	tlsConfig := &tls.Config{MinVersion: cfg.TLSMinVersion()}
+
+	if cfg.ClientCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	pem, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+
+	tlsConfig.ClientCAs = x509.NewCertPool()
+	if !tlsConfig.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, errors.New("client CA file contains no certificates")
+	}
+
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	if cfg.RequireClientCert {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
 }
 
 func (s *Server) Start() error {
@@ -52,8 +118,22 @@ func (s *Server) Start() error {
 	s.Logger.Info().
 		Str("port", s.Config.Server.Port).
 		Str("env", s.Config.Primary.Env).
+		Bool("tls", s.Config.TLS.Enabled).
 		Msg("starting server")
 
+	if s.metricsServer != nil {
+		go func() {
+			s.Logger.Info().Str("port", s.Config.Observability.Metrics.Port).Msg("starting metrics server")
+			if err := s.metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				s.Logger.Error().Err(err).Msg("metrics server failed")
+			}
+		}()
+	}
+
+	if s.Config.TLS.Enabled {
+		return s.httpServer.ListenAndServeTLS(s.Config.TLS.CertFile, s.Config.TLS.KeyFile)
+	}
+
 	return s.httpServer.ListenAndServe()
 }
 
@@ -62,6 +142,12 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		return fmt.Errorf("failed to shutdown HTTP server: %w", err)
 	}
 
+	if s.metricsServer != nil {
+		if err := s.metricsServer.Shutdown(ctx); err != nil {
+			return fmt.Errorf("failed to shutdown metrics server: %w", err)
+		}
+	}
+
 	if err := s.DB.Close(); err != nil {
 		return fmt.Errorf("failed to close database connection: %w", err)
 	}
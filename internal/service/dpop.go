@@ -0,0 +1,142 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/2SSK/jwt/internal/errs"
+	utils "github.com/2SSK/jwt/internal/lib"
+	"github.com/2SSK/jwt/internal/server"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DPoPProofType is the typ header every DPoP proof must carry
+const DPoPProofType = "dpop+jwt"
+
+// dpopReplaySweepInterval limits how often expired jtis are dropped from the replay cache
+const dpopReplaySweepInterval = time.Minute
+
+// DPoPService verifies DPoP proofs (RFC 9449), see config.DPoPConfig. Each proof
+// may only be used once; the jtis seen are kept in memory for as long as their
+// proofs are acceptable, so replicas do not share them and the short
+// ProofMaxAge bounds the replay window across replicas.
+type DPoPService struct {
+	server *server.Server
+
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+func NewDPoPService(s *server.Server) *DPoPService {
+	return &DPoPService{
+		server: s,
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// VerifyProof checks a proof for a request to method and target, and returns the
+// thumbprint of the key that signed it. When the request carries an access token
+// the proof must also hash it in ath.
+func (s *DPoPService) VerifyProof(proof, method, target, accessToken string) (string, error) {
+	cfg := s.server.Config.DPoP
+
+	var key *utils.JWK
+	token, err := jwt.Parse(proof, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); typ != DPoPProofType {
+			return nil, errors.New("invalid typ")
+		}
+
+		var err error
+		if key, err = utils.ParseJWK(token.Header["jwk"]); err != nil {
+			return nil, err
+		}
+		return key.PublicKey()
+	}, jwt.WithValidMethods(cfg.Algorithms))
+	if err != nil || !token.Valid {
+		return "", errs.NewInvalidDPoPProofError("invalid DPoP proof")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", errs.NewInvalidDPoPProofError("invalid DPoP proof claims")
+	}
+
+	if htm, _ := claims["htm"].(string); htm != method {
+		return "", errs.NewInvalidDPoPProofError("DPoP proof was made for another method")
+	}
+	if htu, _ := claims["htu"].(string); !sameTarget(htu, target) {
+		return "", errs.NewInvalidDPoPProofError("DPoP proof was made for another URL")
+	}
+
+	if accessToken != "" {
+		sum := sha256.Sum256([]byte(accessToken))
+		if ath, _ := claims["ath"].(string); ath != base64.RawURLEncoding.EncodeToString(sum[:]) {
+			return "", errs.NewInvalidDPoPProofError("DPoP proof was made for another access token")
+		}
+	}
+
+	now := time.Now()
+	iat, err := claims.GetIssuedAt()
+	if err != nil || iat == nil || iat.After(now.Add(cfg.ClockSkew)) || iat.Before(now.Add(-cfg.ProofMaxAge)) {
+		return "", errs.NewInvalidDPoPProofError("DPoP proof is expired or not yet valid")
+	}
+
+	thumbprint, err := key.Thumbprint()
+	if err != nil {
+		return "", errs.NewInvalidDPoPProofError("invalid DPoP proof key")
+	}
+
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return "", errs.NewInvalidDPoPProofError("DPoP proof has no jti")
+	}
+	if !s.remember(thumbprint+":"+jti, iat.Add(cfg.ProofMaxAge+cfg.ClockSkew), now) {
+		return "", errs.NewInvalidDPoPProofError("DPoP proof has already been used")
+	}
+
+	return thumbprint, nil
+}
+
+// remember records a proof's jti until it expires, and reports false when it was
+// already recorded
+func (s *DPoPService) remember(key string, expiresAt, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > dpopReplaySweepInterval {
+		for k, exp := range s.seen {
+			if now.After(exp) {
+				delete(s.seen, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if exp, ok := s.seen[key]; ok && now.Before(exp) {
+		return false
+	}
+	s.seen[key] = expiresAt
+
+	return true
+}
+
+// sameTarget compares a proof's htu with the request URL, ignoring the query and
+// fragment and the case of the scheme and host (RFC 9449 section 4.3)
+func sameTarget(htu, target string) bool {
+	a, err := url.Parse(htu)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(a.Scheme, b.Scheme) && strings.EqualFold(a.Host, b.Host) && a.EscapedPath() == b.EscapedPath()
+}
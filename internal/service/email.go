@@ -0,0 +1,94 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/2SSK/jwt/internal/config"
+	"github.com/2SSK/jwt/internal/server"
+)
+
+// EmailService sends plain text transactional mail through the configured driver
+type EmailService struct {
+	server *server.Server
+}
+
+func NewEmailService(s *server.Server) *EmailService {
+	return &EmailService{server: s}
+}
+
+func (s *EmailService) Send(ctx context.Context, to, subject, body string) error {
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return errors.New("email headers must not contain line breaks")
+	}
+
+	cfg := s.server.Config.Email
+
+	if cfg.Driver == config.EmailDriverLog {
+		s.server.Logger.Info().
+			Str("to", to).
+			Str("subject", subject).
+			Str("body", body).
+			Msg("email not delivered, log driver in use")
+		return nil
+	}
+
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address: %w", err)
+	}
+	recipient, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address: %w", err)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from.String())
+	fmt.Fprintf(&msg, "To: %s\r\n", recipient.String())
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if cfg.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+
+	addr := net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort))
+
+	// smtp.SendMail has no context support, so bound it by the caller's deadline
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, from.Address, []string{recipient.Address}, msg.Bytes())
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SendAsync sends in the background and logs failures. Callers use it where the
+// response must not reveal, through latency or errors, whether mail was sent.
+func (s *EmailService) SendAsync(to, subject, body string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := s.Send(ctx, to, subject, body); err != nil {
+			s.server.Logger.Error().Err(err).Str("subject", subject).Msg("failed to send email")
+		}
+	}()
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/2SSK/jwt/internal/errs"
+	"github.com/2SSK/jwt/internal/model/lockout"
+	"github.com/2SSK/jwt/internal/model/user"
+	"github.com/2SSK/jwt/internal/repository"
+	"github.com/2SSK/jwt/internal/server"
+	"github.com/google/uuid"
+)
+
+// LockoutService throttles password logins and second factor attempts per account
+// and per client IP, see config.LockoutConfig
+type LockoutService struct {
+	server      *server.Server
+	lockoutRepo repository.LockoutRepository
+	userRepo    repository.UserRepository
+	email       *EmailService
+}
+
+func NewLockoutService(s *server.Server, lockoutRepo repository.LockoutRepository, userRepo repository.UserRepository, email *EmailService) *LockoutService {
+	return &LockoutService{
+		server:      s,
+		lockoutRepo: lockoutRepo,
+		userRepo:    userRepo,
+		email:       email,
+	}
+}
+
+// Check rejects a login attempt while the account or IP is delayed or locked out.
+// It runs before the password is verified, so blocked attempts reveal nothing.
+func (s *LockoutService) Check(ctx context.Context, email, ip string) error {
+	cfg := s.server.Config.Lockout
+	if !cfg.Enabled {
+		return nil
+	}
+
+	now := time.Now()
+
+	account, err := s.lockoutRepo.GetThrottle(ctx, lockout.ScopeAccount, accountSubject(email))
+	if err != nil {
+		return err
+	}
+	if wait := account.Blocked(now); wait > 0 {
+		if account.Failures >= cfg.AccountThreshold {
+			return errs.NewAccountLockedError("account temporarily locked after too many failed login attempts", wait)
+		}
+		return errs.NewTooManyRequestsError("too many failed login attempts, try again later", true, wait)
+	}
+
+	if ip == "" {
+		return nil
+	}
+
+	client, err := s.lockoutRepo.GetThrottle(ctx, lockout.ScopeIP, ip)
+	if err != nil {
+		return err
+	}
+	if wait := client.Blocked(now); wait > 0 {
+		return errs.NewTooManyRequestsError("too many failed login attempts, try again later", true, wait)
+	}
+
+	return nil
+}
+
+// RecordFailure counts a failed login against the account and IP. u is nil when
+// the email does not belong to an account; the counters still apply, but nobody
+// is notified.
+func (s *LockoutService) RecordFailure(ctx context.Context, email, ip string, u *user.User) error {
+	cfg := s.server.Config.Lockout
+	if !cfg.Enabled {
+		return nil
+	}
+
+	account, err := s.recordFailure(ctx, lockout.ScopeAccount, accountSubject(email), cfg.AccountThreshold, cfg.AccountLockoutDuration)
+	if err != nil {
+		return err
+	}
+
+	if account.Failures == cfg.AccountThreshold {
+		logger := s.server.Logger.Warn().Str("ip", ip)
+		if u != nil {
+			logger = logger.Str("user_id", u.ID.String())
+		}
+		logger.Int("failures", account.Failures).Msg("account locked after repeated failed logins")
+
+		if u != nil && u.Email != nil {
+			s.notifyLocked(*u.Email, account)
+		}
+	}
+
+	if ip == "" {
+		return nil
+	}
+
+	client, err := s.recordFailure(ctx, lockout.ScopeIP, ip, cfg.IPThreshold, cfg.IPLockoutDuration)
+	if err != nil {
+		return err
+	}
+
+	if client.Failures == cfg.IPThreshold {
+		s.server.Logger.Warn().Str("ip", ip).Int("failures", client.Failures).Msg("ip blocked after repeated failed logins")
+	}
+
+	return nil
+}
+
+// RecordSuccess clears the account's failures after a correct password. IP
+// counters are left alone, one good password must not excuse a spraying client.
+func (s *LockoutService) RecordSuccess(ctx context.Context, email string) error {
+	if !s.server.Config.Lockout.Enabled {
+		return nil
+	}
+
+	_, err := s.lockoutRepo.ResetThrottle(ctx, lockout.ScopeAccount, accountSubject(email))
+	return err
+}
+
+// Unlock lifts an account lockout and clears its failure count
+func (s *LockoutService) Unlock(ctx context.Context, userID uuid.UUID) error {
+	u, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u == nil || u.Email == nil {
+		return errs.NewNotFoundError("user not found", true, nil)
+	}
+
+	_, err = s.lockoutRepo.ResetThrottle(ctx, lockout.ScopeAccount, accountSubject(*u.Email))
+	return err
+}
+
+func (s *LockoutService) recordFailure(ctx context.Context, scope, subject string, threshold int, lockoutDuration time.Duration) (*lockout.Throttle, error) {
+	cfg := s.server.Config.Lockout
+	now := time.Now()
+
+	throttle, err := s.lockoutRepo.RecordFailure(ctx, scope, subject, now.Add(-cfg.FailureWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	var wait time.Duration
+	switch {
+	case throttle.Failures >= threshold:
+		wait = lockoutDuration
+	case throttle.Failures >= cfg.DelayAfter:
+		wait = s.backoff(throttle.Failures - cfg.DelayAfter)
+	default:
+		return throttle, nil
+	}
+
+	lockedUntil := now.Add(wait)
+	if err := s.lockoutRepo.SetLockedUntil(ctx, scope, subject, lockedUntil); err != nil {
+		return nil, err
+	}
+	throttle.LockedUntil = &lockedUntil
+
+	return throttle, nil
+}
+
+// backoff doubles the base delay for every failure past the free ones, up to the maximum
+func (s *LockoutService) backoff(n int) time.Duration {
+	cfg := s.server.Config.Lockout
+
+	delay := cfg.BaseDelay
+	for i := 0; i < n && delay < cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, cfg.MaxDelay)
+}
+
+func (s *LockoutService) notifyLocked(email string, throttle *lockout.Throttle) {
+	body := fmt.Sprintf(
+		"Your account was temporarily locked after %d failed sign-in attempts.\n\nYou can sign in again after %s. If these attempts were not you, we recommend changing your password once you are signed in.\n",
+		throttle.Failures, throttle.LockedUntil.UTC().Format(time.RFC1123),
+	)
+
+	s.email.SendAsync(email, "Your account has been temporarily locked", body)
+}
+
+func accountSubject(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
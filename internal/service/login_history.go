@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/2SSK/jwt/internal/model/loginhistory"
+	"github.com/2SSK/jwt/internal/model/user"
+	"github.com/2SSK/jwt/internal/repository"
+	"github.com/2SSK/jwt/internal/server"
+	"github.com/google/uuid"
+)
+
+// LoginHistoryService records login attempts and warns users about logins from
+// devices or networks they have not used before
+type LoginHistoryService struct {
+	server           *server.Server
+	loginHistoryRepo repository.LoginHistoryRepository
+	email            *EmailService
+}
+
+func NewLoginHistoryService(s *server.Server, loginHistoryRepo repository.LoginHistoryRepository, email *EmailService) *LoginHistoryService {
+	return &LoginHistoryService{
+		server:           s,
+		loginHistoryRepo: loginHistoryRepo,
+		email:            email,
+	}
+}
+
+// RecordFailure stores a failed attempt. userID is nil when the email matched no
+// account. Recording is best effort and never changes the outcome of the login.
+func (s *LoginHistoryService) RecordFailure(ctx context.Context, userID *uuid.UUID, email *string, method, reason string, client user.ClientInfo) {
+	event := newLoginEvent(method, client)
+	event.UserID = userID
+	event.Email = email
+	event.FailureReason = &reason
+
+	s.server.Metrics.Logins.WithLabelValues(method, "failure", reason).Inc()
+
+	if _, err := s.loginHistoryRepo.CreateEvent(ctx, event); err != nil {
+		s.server.Logger.Error().Err(err).Str("method", method).Msg("failed to record login attempt")
+	}
+}
+
+// RecordSuccess stores a successful login and emails the user when it came from a
+// device or network not seen in their earlier logins. The first ever login does
+// not alert.
+func (s *LoginHistoryService) RecordSuccess(ctx context.Context, u *user.User, method string, client user.ClientInfo) {
+	event := newLoginEvent(method, client)
+	event.UserID = &u.ID
+	event.Email = u.Email
+	event.Success = true
+
+	s.server.Metrics.Logins.WithLabelValues(method, "success", "").Inc()
+
+	logger := s.server.Logger.With().Str("user_id", u.ID.String()).Logger()
+
+	familiarity, err := s.loginHistoryRepo.GetFamiliarity(ctx, u.ID, event.DeviceFingerprint, event.Network)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to check login familiarity")
+	}
+
+	if _, err := s.loginHistoryRepo.CreateEvent(ctx, event); err != nil {
+		logger.Error().Err(err).Msg("failed to record login")
+		return
+	}
+
+	if familiarity == nil || !familiarity.HasHistory || (familiarity.KnownDevice && familiarity.KnownNetwork) {
+		return
+	}
+
+	if u.Email != nil {
+		s.notifyNewDevice(*u.Email, event)
+	}
+}
+
+func (s *LoginHistoryService) GetLogins(ctx context.Context, userID uuid.UUID, limit, offset int) ([]loginhistory.EventResponse, error) {
+	events, err := s.loginHistoryRepo.GetEventsByUserID(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]loginhistory.EventResponse, 0, len(events))
+	for _, e := range events {
+		responses = append(responses, loginhistory.EventResponse{
+			ID:                e.ID,
+			Method:            e.Method,
+			Success:           e.Success,
+			FailureReason:     e.FailureReason,
+			IPAddress:         e.IPAddress,
+			UserAgent:         e.UserAgent,
+			DeviceFingerprint: e.DeviceFingerprint,
+			CreatedAt:         e.CreatedAt,
+		})
+	}
+
+	return responses, nil
+}
+
+func (s *LoginHistoryService) notifyNewDevice(email string, event *loginhistory.Event) {
+	userAgent := event.UserAgent
+	if userAgent == "" {
+		userAgent = "unknown"
+	}
+
+	body := fmt.Sprintf(
+		"There was a new sign-in to your account from a device or network we have not seen before.\n\nTime: %s\nIP address: %s\nDevice: %s\n\nIf this was you, no action is needed. If not, change your password and review your recent logins.\n",
+		event.CreatedAt.UTC().Format(time.RFC1123), event.IPAddress, userAgent,
+	)
+
+	s.email.SendAsync(email, "New sign-in to your account", body)
+}
+
+func newLoginEvent(method string, client user.ClientInfo) *loginhistory.Event {
+	return &loginhistory.Event{
+		Method:            method,
+		IPAddress:         client.IPAddress,
+		Network:           networkOf(client.IPAddress),
+		UserAgent:         client.UserAgent,
+		DeviceFingerprint: client.DeviceFingerprint,
+	}
+}
+
+// networkOf groups addresses that typically belong to the same connection: the /24
+// for IPv4 and the /48 for IPv6
+func networkOf(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
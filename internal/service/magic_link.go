@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/2SSK/jwt/internal/errs"
+	utils "github.com/2SSK/jwt/internal/lib"
+	"github.com/2SSK/jwt/internal/model/loginhistory"
+	"github.com/2SSK/jwt/internal/model/magiclink"
+	"github.com/2SSK/jwt/internal/model/user"
+	"github.com/2SSK/jwt/internal/repository"
+	"github.com/2SSK/jwt/internal/server"
+	"github.com/2SSK/jwt/internal/tracing"
+)
+
+const (
+	magicLinkCodeLength = 6
+	// magicLinkMaxInFlight bounds the links being issued in the background at once
+	magicLinkMaxInFlight = 32
+)
+
+type MagicLinkService struct {
+	server        *server.Server
+	userRepo      repository.UserRepository
+	magicLinkRepo repository.MagicLinkRepository
+	email         *EmailService
+	userService   *UserService
+	loginHistory  *LoginHistoryService
+	// issuing holds a slot per link being issued
+	issuing chan struct{}
+}
+
+func NewMagicLinkService(s *server.Server, userRepo repository.UserRepository, magicLinkRepo repository.MagicLinkRepository, email *EmailService, userService *UserService, loginHistory *LoginHistoryService) *MagicLinkService {
+	return &MagicLinkService{
+		server:        s,
+		userRepo:      userRepo,
+		magicLinkRepo: magicLinkRepo,
+		email:         email,
+		userService:   userService,
+		loginHistory:  loginHistory,
+		issuing:       make(chan struct{}, magicLinkMaxInFlight),
+	}
+}
+
+// Request emails a login link and code when the address belongs to an account.
+// The response is identical either way, and issuing happens in the background so
+// response time does not reveal whether the account exists. At most
+// magicLinkMaxInFlight links are issued at once; requests beyond that are dropped.
+func (s *MagicLinkService) Request(ctx context.Context, payload *magiclink.RequestPayload) (*magiclink.RequestResponse, error) {
+	ctx, span := tracing.Start(ctx, "MagicLinkService.Request")
+	defer span.End()
+
+	u, err := s.userRepo.GetUserByEmail(ctx, payload.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	if u != nil {
+		select {
+		case s.issuing <- struct{}{}:
+			go func() {
+				defer func() { <-s.issuing }()
+				s.issue(u)
+			}()
+		default:
+			s.server.Logger.Warn().Str("user_id", u.ID.String()).Msg("too many magic links being issued, request ignored")
+		}
+	}
+
+	return &magiclink.RequestResponse{
+		Message: "if an account exists for this email, a sign-in link has been sent",
+	}, nil
+}
+
+func (s *MagicLinkService) issue(u *user.User) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cfg := s.server.Config.MagicLink
+	logger := s.server.Logger.With().Str("user_id", u.ID.String()).Logger()
+
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate magic link token")
+		return
+	}
+	code, err := utils.RandomDigits(magicLinkCodeLength)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate magic link code")
+		return
+	}
+
+	created, err := s.magicLinkRepo.CreateMagicLink(ctx, &magiclink.MagicLink{
+		UserID:    u.ID,
+		TokenHash: utils.HashToken(token),
+		CodeHash:  utils.HashToken(code),
+		ExpiresAt: time.Now().Add(cfg.TTL),
+	}, cfg.MaxPerHour)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create magic link")
+		return
+	}
+	if created == nil {
+		logger.Warn().Msg("magic link hourly limit reached, request ignored")
+		return
+	}
+
+	link, err := url.Parse(cfg.LinkURL)
+	if err != nil {
+		logger.Error().Err(err).Msg("invalid magic link url")
+		return
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	body := fmt.Sprintf(
+		"Use the link below to sign in:\n\n%s\n\nOr enter this code: %s\n\nThe link and code expire in %d minutes and can be used once. If you did not request this, you can ignore this email.\n",
+		link.String(), code, int(cfg.TTL.Minutes()),
+	)
+
+	if err := s.email.Send(ctx, *u.Email, "Your sign-in link", body); err != nil {
+		logger.Error().Err(err).Msg("failed to send magic link email")
+	}
+}
+
+// Verify redeems a magic link token or emailed code. The result is the same as a
+// password login, including an MFA challenge when the user has a second factor.
+func (s *MagicLinkService) Verify(ctx context.Context, payload *magiclink.VerifyPayload, client user.ClientInfo) (*user.LoginResponse, *user.MFAChallengeResponse, error) {
+	ctx, span := tracing.Start(ctx, "MagicLinkService.Verify")
+	defer span.End()
+
+	var (
+		link *magiclink.MagicLink
+		err  error
+	)
+
+	if payload.Token != "" {
+		link, err = s.magicLinkRepo.GetMagicLinkByTokenHash(ctx, utils.HashToken(payload.Token))
+		if err != nil {
+			return nil, nil, err
+		}
+		if !s.usable(link) {
+			return nil, nil, errs.NewUnauthorizedError("invalid or expired magic link", true)
+		}
+	} else {
+		link, err = s.verifyCode(ctx, payload.Email, payload.Code, client)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	consumed, err := s.magicLinkRepo.ConsumeMagicLink(ctx, link.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !consumed {
+		return nil, nil, errs.NewUnauthorizedError("invalid or expired magic link", true)
+	}
+
+	u, err := s.userRepo.GetUserByID(ctx, link.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u == nil {
+		return nil, nil, errs.NewUnauthorizedError("invalid or expired magic link", true)
+	}
+
+	return s.userService.CompleteFirstFactor(ctx, u, loginhistory.MethodMagicLink, client)
+}
+
+// verifyCode checks a code against the user's current link. Unknown addresses fail
+// the same way as wrong codes. Every attempt is counted before the code is compared,
+// so parallel guesses cannot get past the attempt limit.
+func (s *MagicLinkService) verifyCode(ctx context.Context, email, code string, client user.ClientInfo) (*magiclink.MagicLink, error) {
+	invalid := errs.NewUnauthorizedError("invalid or expired code", true)
+
+	u, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, invalid
+	}
+
+	link, err := s.magicLinkRepo.GetActiveMagicLinkByUserID(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !s.usable(link) {
+		return nil, invalid
+	}
+
+	counted, err := s.magicLinkRepo.IncrementMagicLinkAttempts(ctx, link.ID, s.server.Config.MagicLink.MaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if !counted {
+		return nil, invalid
+	}
+
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(code)), []byte(link.CodeHash)) != 1 {
+		s.loginHistory.RecordFailure(ctx, &u.ID, u.Email, loginhistory.MethodMagicLink, loginhistory.FailureInvalidMagicCode, client)
+		return nil, invalid
+	}
+
+	return link, nil
+}
+
+func (s *MagicLinkService) usable(link *magiclink.MagicLink) bool {
+	return link != nil &&
+		link.ConsumedAt == nil &&
+		time.Now().Before(link.ExpiresAt) &&
+		link.Attempts < s.server.Config.MagicLink.MaxAttempts
+}
@@ -0,0 +1,407 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/2SSK/jwt/internal/errs"
+	utils "github.com/2SSK/jwt/internal/lib"
+	"github.com/2SSK/jwt/internal/model/loginhistory"
+	"github.com/2SSK/jwt/internal/model/mfa"
+	"github.com/2SSK/jwt/internal/model/user"
+	"github.com/2SSK/jwt/internal/repository"
+	"github.com/2SSK/jwt/internal/server"
+	"github.com/2SSK/jwt/internal/tracing"
+	"github.com/google/uuid"
+)
+
+const recoveryCodeCount = 10
+
+type MFAService struct {
+	server       *server.Server
+	userRepo     repository.UserRepository
+	mfaRepo      repository.MFARepository
+	userService  *UserService
+	lockout      *LockoutService
+	loginHistory *LoginHistoryService
+}
+
+func NewMFAService(s *server.Server, userRepo repository.UserRepository, mfaRepo repository.MFARepository, userService *UserService, lockout *LockoutService, loginHistory *LoginHistoryService) *MFAService {
+	return &MFAService{
+		server:       s,
+		userRepo:     userRepo,
+		mfaRepo:      mfaRepo,
+		userService:  userService,
+		lockout:      lockout,
+		loginHistory: loginHistory,
+	}
+}
+
+func (s *MFAService) Status(ctx context.Context, userID uuid.UUID) (*mfa.StatusResponse, error) {
+	totp, err := s.mfaRepo.GetTOTPByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining, err := s.mfaRepo.CountUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mfa.StatusResponse{
+		TOTPEnabled:            totp.Confirmed(),
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+// EnrollTOTP generates a new unconfirmed secret. It only takes effect once
+// ConfirmTOTP sees a valid code from the authenticator.
+func (s *MFAService) EnrollTOTP(ctx context.Context, userID uuid.UUID) (*mfa.TOTPEnrollResponse, error) {
+	u, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errs.NewNotFoundError("user not found", true, nil)
+	}
+
+	existing, err := s.mfaRepo.GetTOTPByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.Confirmed() {
+		return nil, errs.NewBadRequestError("TOTP is already enabled", true, nil, nil, nil)
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, keyID, err := s.encryptTOTPSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaRepo.UpsertPendingTOTP(ctx, userID, encrypted, keyID); err != nil {
+		return nil, err
+	}
+
+	account := u.ID.String()
+	if u.Email != nil {
+		account = *u.Email
+	}
+
+	return &mfa.TOTPEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(s.server.Config.Auth.MFAIssuer, account, secret),
+	}, nil
+}
+
+// ConfirmTOTP activates a pending enrollment and issues the first set of recovery codes
+func (s *MFAService) ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) (*mfa.RecoveryCodesResponse, error) {
+	totp, err := s.mfaRepo.GetTOTPByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if totp == nil {
+		return nil, errs.NewBadRequestError("no pending TOTP enrollment", true, nil, nil, nil)
+	}
+	if totp.Confirmed() {
+		return nil, errs.NewBadRequestError("TOTP is already enabled", true, nil, nil, nil)
+	}
+
+	secret, err := s.decryptTOTPSecret(ctx, totp)
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, errs.NewBadRequestError("invalid TOTP code", true, nil, []errs.FieldError{
+			{Field: "code", Error: "is invalid or expired"},
+		}, nil)
+	}
+
+	if err := s.mfaRepo.ConfirmTOTP(ctx, userID, step); err != nil {
+		return nil, err
+	}
+
+	return s.replaceRecoveryCodes(ctx, userID)
+}
+
+// DisableTOTP removes the authenticator and all recovery codes after proving possession of either
+func (s *MFAService) DisableTOTP(ctx context.Context, userID uuid.UUID, payload *mfa.DisableTOTPPayload) error {
+	totp, err := s.mfaRepo.GetTOTPByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !totp.Confirmed() {
+		return errs.NewBadRequestError("TOTP is not enabled", true, nil, nil, nil)
+	}
+
+	ok, err := s.checkSecondFactor(ctx, totp, payload.Code, payload.RecoveryCode)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errs.NewUnauthorizedError("invalid MFA code", true)
+	}
+
+	return s.mfaRepo.DeleteTOTP(ctx, userID)
+}
+
+// RegenerateRecoveryCodes invalidates all existing recovery codes and returns a new set
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) (*mfa.RecoveryCodesResponse, error) {
+	totp, err := s.mfaRepo.GetTOTPByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !totp.Confirmed() {
+		return nil, errs.NewBadRequestError("TOTP is not enabled", true, nil, nil, nil)
+	}
+
+	ok, err := s.checkSecondFactor(ctx, totp, code, "")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errs.NewUnauthorizedError("invalid MFA code", true)
+	}
+
+	return s.replaceRecoveryCodes(ctx, userID)
+}
+
+// Verify completes a login challenge with a TOTP or recovery code and issues tokens
+func (s *MFAService) Verify(ctx context.Context, payload *mfa.VerifyPayload, client user.ClientInfo) (*user.LoginResponse, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.Verify")
+	defer span.End()
+
+	method := mfa.MethodTOTP
+	if payload.RecoveryCode != "" {
+		method = mfa.MethodRecoveryCode
+	}
+
+	challenge, err := s.AttemptChallenge(ctx, payload.MFAToken, method, client)
+	if err != nil {
+		return nil, err
+	}
+
+	totp, err := s.mfaRepo.GetTOTPByUserID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.checkSecondFactor(ctx, totp, payload.Code, payload.RecoveryCode)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, s.FailChallenge(ctx, challenge, method, client)
+	}
+
+	return s.CompleteChallenge(ctx, challenge, method, client)
+}
+
+// ResolveChallenge looks up a pending login challenge by its token, rejecting
+// consumed, expired and exhausted ones
+func (s *MFAService) ResolveChallenge(ctx context.Context, token string) (*mfa.Challenge, error) {
+	challenge, err := s.mfaRepo.GetChallengeByTokenHash(ctx, utils.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil || challenge.ConsumedAt != nil || time.Now().After(challenge.ExpiresAt) {
+		return nil, errs.NewUnauthorizedError("invalid or expired MFA token", true)
+	}
+	if challenge.Attempts >= MFAChallengeMaxAttempts {
+		return nil, errs.NewUnauthorizedError("too many failed MFA attempts, please log in again", true)
+	}
+
+	return challenge, nil
+}
+
+// AttemptChallenge resolves a login challenge and counts an attempt to complete it,
+// before the second factor is checked. The attempt is counted atomically so parallel
+// guesses cannot get past MFAChallengeMaxAttempts, and it is refused while the
+// account or IP is locked out.
+func (s *MFAService) AttemptChallenge(ctx context.Context, token, method string, client user.ClientInfo) (*mfa.Challenge, error) {
+	challenge, err := s.ResolveChallenge(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := s.userRepo.GetUserByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errs.NewUnauthorizedError("invalid or expired MFA token", true)
+	}
+	if u.Email != nil {
+		if err := s.lockout.Check(ctx, *u.Email, client.IPAddress); err != nil {
+			s.loginHistory.RecordFailure(ctx, &u.ID, u.Email, method, loginhistory.FailureThrottled, client)
+			return nil, err
+		}
+	}
+
+	counted, err := s.mfaRepo.IncrementChallengeAttempts(ctx, challenge.ID, MFAChallengeMaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if !counted {
+		return nil, errs.NewUnauthorizedError("too many failed MFA attempts, please log in again", true)
+	}
+
+	return challenge, nil
+}
+
+// FailChallenge records a failed second factor, in the login history and against
+// the lockout counters, and returns the error to send to the client. The attempt
+// itself was counted by AttemptChallenge.
+func (s *MFAService) FailChallenge(ctx context.Context, challenge *mfa.Challenge, method string, client user.ClientInfo) error {
+	s.loginHistory.RecordFailure(ctx, &challenge.UserID, nil, method, loginhistory.FailureInvalidMFACode, client)
+
+	u, err := s.userRepo.GetUserByID(ctx, challenge.UserID)
+	if err != nil {
+		return err
+	}
+	if u != nil && u.Email != nil {
+		if err := s.lockout.RecordFailure(ctx, *u.Email, client.IPAddress, u); err != nil {
+			return err
+		}
+	}
+
+	return errs.NewUnauthorizedError("invalid MFA code", true)
+}
+
+// CompleteChallenge consumes the challenge and issues tokens for its user
+func (s *MFAService) CompleteChallenge(ctx context.Context, challenge *mfa.Challenge, method string, client user.ClientInfo) (*user.LoginResponse, error) {
+	consumed, err := s.mfaRepo.ConsumeChallenge(ctx, challenge.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, errs.NewUnauthorizedError("invalid or expired MFA token", true)
+	}
+
+	u, err := s.userRepo.GetUserByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errs.NewUnauthorizedError("invalid or expired MFA token", true)
+	}
+
+	if u.Email != nil {
+		if err := s.lockout.RecordSuccess(ctx, *u.Email); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.userService.NewLoginResponse(ctx, u, []string{challenge.FirstFactor, method}, client)
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused recovery code.
+// Both are single use: TOTP steps cannot be replayed and recovery codes are burned.
+func (s *MFAService) checkSecondFactor(ctx context.Context, totp *mfa.TOTP, code, recoveryCode string) (bool, error) {
+	if !totp.Confirmed() {
+		return false, nil
+	}
+
+	if recoveryCode != "" {
+		return s.mfaRepo.UseRecoveryCode(ctx, totp.UserID, hashRecoveryCode(recoveryCode))
+	}
+
+	secret, err := s.decryptTOTPSecret(ctx, totp)
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	return s.mfaRepo.UseTOTPStep(ctx, totp.UserID, step)
+}
+
+// encryptTOTPSecret seals a secret with the current mfa encryption key and returns
+// the key's id to store with it
+func (s *MFAService) encryptTOTPSecret(secret string) (string, string, error) {
+	cfg := s.server.Config.MFA
+	key, ok := cfg.Key(cfg.KeyID())
+	if !ok {
+		return "", "", errors.New("mfa encryption key is not configured")
+	}
+
+	encrypted, err := utils.EncryptStringWithKey(key, secret)
+	if err != nil {
+		return "", "", err
+	}
+	return encrypted, cfg.KeyID(), nil
+}
+
+// decryptTOTPSecret opens a stored secret with the key it was sealed with. Secrets
+// sealed with a retired key, or with auth.secret_key before mfa had its own key,
+// are re-encrypted with the current key so the old one can be dropped.
+func (s *MFAService) decryptTOTPSecret(ctx context.Context, totp *mfa.TOTP) (string, error) {
+	cfg := s.server.Config.MFA
+
+	var secret string
+	var err error
+	if totp.KeyID == "" {
+		secret, err = utils.DecryptString(s.server.Config.Auth.SecretKey, totp.Secret)
+	} else if key, ok := cfg.Key(totp.KeyID); ok {
+		secret, err = utils.DecryptStringWithKey(key, totp.Secret)
+	} else {
+		return "", fmt.Errorf("mfa encryption key %q is not configured", totp.KeyID)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if totp.KeyID != cfg.KeyID() {
+		encrypted, keyID, err := s.encryptTOTPSecret(secret)
+		if err == nil {
+			err = s.mfaRepo.UpdateTOTPSecret(ctx, totp.UserID, totp.Secret, encrypted, keyID)
+		}
+		if err != nil {
+			s.server.Logger.Error().Err(err).Str("user_id", totp.UserID.String()).Msg("failed to re-encrypt TOTP secret")
+		}
+	}
+
+	return secret, nil
+}
+
+func (s *MFAService) replaceRecoveryCodes(ctx context.Context, userID uuid.UUID) (*mfa.RecoveryCodesResponse, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for range recoveryCodeCount {
+		code := generateRecoveryCode()
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	if err := s.mfaRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return &mfa.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// generateRecoveryCode returns a code like "k7m2p-x9qrt" (50 bits of entropy)
+func generateRecoveryCode() string {
+	text := strings.ToLower(rand.Text())
+	return text[:5] + "-" + text[5:10]
+}
+
+// hashRecoveryCode normalizes user input so dashes, spacing and case don't matter
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return utils.HashToken(normalized)
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+
+	"github.com/2SSK/jwt/internal/errs"
+	"github.com/2SSK/jwt/internal/model/loginhistory"
+	"github.com/2SSK/jwt/internal/model/mfa"
+	"github.com/2SSK/jwt/internal/model/user"
+	"github.com/2SSK/jwt/internal/repository"
+	"github.com/2SSK/jwt/internal/server"
+	"github.com/2SSK/jwt/internal/tracing"
+	"github.com/google/uuid"
+)
+
+// ReauthService lets a signed in user prove their identity again to pass
+// RequireRecentAuth on sensitive routes, without logging out. Failed attempts
+// count towards the login lockout, see LockoutService.
+type ReauthService struct {
+	server      *server.Server
+	userRepo    repository.UserRepository
+	mfaRepo     repository.MFARepository
+	userService *UserService
+	mfaService  *MFAService
+	lockout     *LockoutService
+}
+
+func NewReauthService(s *server.Server, repos *repository.Repositories, userService *UserService, mfaService *MFAService, lockout *LockoutService) *ReauthService {
+	return &ReauthService{
+		server:      s,
+		userRepo:    repos.User,
+		mfaRepo:     repos.MFA,
+		userService: userService,
+		mfaService:  mfaService,
+		lockout:     lockout,
+	}
+}
+
+// Reauthenticate verifies every factor in the payload and upgrades the current
+// session. Password plus a second factor gives a multi-factor level.
+func (s *ReauthService) Reauthenticate(ctx context.Context, userID, sessionID uuid.UUID, payload *user.ReauthenticatePayload, client user.ClientInfo) (*user.TokenResponse, error) {
+	ctx, span := tracing.Start(ctx, "ReauthService.Reauthenticate")
+	defer span.End()
+
+	u, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errs.NewUnauthorizedError("invalid authentication", true)
+	}
+
+	email := ""
+	if u.Email != nil {
+		email = *u.Email
+	}
+
+	if err := s.lockout.Check(ctx, email, client.IPAddress); err != nil {
+		return nil, err
+	}
+
+	var factors []string
+
+	if payload.Password != "" {
+		if u.Password == nil || s.userService.VerifyPassword(*u.Password, payload.Password) != nil {
+			return nil, s.failed(ctx, email, u, client, "invalid password")
+		}
+		factors = append(factors, loginhistory.MethodPassword)
+	}
+
+	if payload.Code != "" || payload.RecoveryCode != "" {
+		totp, err := s.mfaRepo.GetTOTPByUserID(ctx, u.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		ok, err := s.mfaService.checkSecondFactor(ctx, totp, payload.Code, payload.RecoveryCode)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, s.failed(ctx, email, u, client, "invalid MFA code")
+		}
+
+		method := mfa.MethodTOTP
+		if payload.RecoveryCode != "" {
+			method = mfa.MethodRecoveryCode
+		}
+		factors = append(factors, method)
+	}
+
+	if err := s.lockout.RecordSuccess(ctx, email); err != nil {
+		return nil, err
+	}
+
+	return s.userService.ReauthenticateSession(ctx, u, sessionID, factors)
+}
+
+func (s *ReauthService) failed(ctx context.Context, email string, u *user.User, client user.ClientInfo, message string) error {
+	if err := s.lockout.RecordFailure(ctx, email, client.IPAddress, u); err != nil {
+		return err
+	}
+	return errs.NewUnauthorizedError(message, true)
+}
@@ -7,16 +7,60 @@ import (
 )
 
 type Services struct {
-	Auth       *AuthService
-	User       *UserService
-	AuthHelper *utils.AuthHelper
+	Auth         *AuthService
+	User         *UserService
+	MFA          *MFAService
+	WebAuthn     *WebAuthnService
+	Reauth       *ReauthService
+	MagicLink    *MagicLinkService
+	Email        *EmailService
+	Lockout      *LockoutService
+	LoginHistory *LoginHistoryService
+	Session      *SessionService
+	DPoP         *DPoPService
+	Webhook      *WebhookService
+	Token        *TokenService
+	AuthHelper   *utils.AuthHelper
 }
 
 func NewServices(s *server.Server, repos *repository.Repositories) (*Services, error) {
 	authHelper := utils.NewAuthHelper(repos.User)
+	webhookService := NewWebhookService(s, repos.Webhook)
+	emailService := NewEmailService(s)
+	lockoutService := NewLockoutService(s, repos.Lockout, repos.User, emailService)
+	loginHistoryService := NewLoginHistoryService(s, repos.LoginHistory, emailService)
+	sessionService := NewSessionService(s, repos.Session)
+	tokenService, err := NewTokenService(s, repos.Token, repos.Session)
+	if err != nil {
+		return nil, err
+	}
+
+	userService, err := NewUserService(s, repos, webhookService, lockoutService, loginHistoryService, sessionService, tokenService)
+	if err != nil {
+		return nil, err
+	}
+
+	mfaService := NewMFAService(s, repos.User, repos.MFA, userService, lockoutService, loginHistoryService)
+
+	webauthnService, err := NewWebAuthnService(s, repos, mfaService, userService)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Services{
-		User:       NewUserService(repos.User, s.Config.Auth.SecretKey),
-		Auth:       NewAuthService(s),
-		AuthHelper: authHelper,
+		User:         userService,
+		MFA:          mfaService,
+		WebAuthn:     webauthnService,
+		Reauth:       NewReauthService(s, repos, userService, mfaService, lockoutService),
+		MagicLink:    NewMagicLinkService(s, repos.User, repos.MagicLink, emailService, userService, loginHistoryService),
+		Email:        emailService,
+		Lockout:      lockoutService,
+		LoginHistory: loginHistoryService,
+		Session:      sessionService,
+		DPoP:         NewDPoPService(s),
+		Auth:         NewAuthService(s),
+		Webhook:      webhookService,
+		Token:        tokenService,
+		AuthHelper:   authHelper,
 	}, nil
 }
@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/2SSK/jwt/internal/config"
+	"github.com/2SSK/jwt/internal/errs"
+	"github.com/2SSK/jwt/internal/model/loginhistory"
+	"github.com/2SSK/jwt/internal/model/mfa"
+	"github.com/2SSK/jwt/internal/model/session"
+	"github.com/2SSK/jwt/internal/model/user"
+	"github.com/2SSK/jwt/internal/repository"
+	"github.com/2SSK/jwt/internal/server"
+	"github.com/2SSK/jwt/internal/tracing"
+	"github.com/google/uuid"
+)
+
+// sessionTouchInterval limits how often a session's last seen time is written back
+const sessionTouchInterval = time.Minute
+
+// SessionService tracks where a user is signed in. Every token carries the id of
+// the session it was issued for, and requests made with the tokens of a revoked or
+// expired session are rejected. See config.SessionConfig for the lifetimes.
+type SessionService struct {
+	server      *server.Server
+	sessionRepo repository.SessionRepository
+}
+
+func NewSessionService(s *server.Server, sessionRepo repository.SessionRepository) *SessionService {
+	return &SessionService{
+		server:      s,
+		sessionRepo: sessionRepo,
+	}
+}
+
+// Create starts a session for a user who just logged in with the given factors, in
+// the order they were completed. When the user is over the concurrent session limit
+// afterwards, their oldest sessions are signed out.
+func (s *SessionService) Create(ctx context.Context, u *user.User, factors []string, client user.ClientInfo) (*session.Session, error) {
+	ctx, span := tracing.Start(ctx, "SessionService.Create")
+	defer span.End()
+
+	cfg := s.server.Config.Sessions
+
+	// Only configured clients are recorded, anything else gets the defaults
+	clientID := client.ClientID
+	_, hasLifetime := cfg.Clients[clientID]
+	_, hasFormat := s.server.Config.Tokens.Clients[clientID]
+	if !hasLifetime && !hasFormat {
+		clientID = ""
+	}
+
+	deviceName := client.DeviceName
+	if deviceName == "" {
+		deviceName = describeUserAgent(client.UserAgent)
+	}
+
+	now := time.Now()
+	absoluteExpiresAt := now.Add(cfg.MaxAge)
+	amr, acr := authenticationOf(factors)
+
+	sess, err := s.sessionRepo.CreateSession(ctx, &session.Session{
+		UserID:            u.ID,
+		ClientID:          clientID,
+		DeviceName:        deviceName,
+		IPAddress:         client.IPAddress,
+		UserAgent:         client.UserAgent,
+		ExpiresAt:         earliest(now.Add(s.Lifetime(u, clientID).RefreshTTL), absoluteExpiresAt),
+		AbsoluteExpiresAt: absoluteExpiresAt,
+		AuthTime:          now,
+		AMR:               amr,
+		ACR:               acr,
+		DPoPJKT:           client.DPoPJKT,
+		CertThumbprint:    client.CertThumbprint,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.MaxPerUser > 0 {
+		evicted, err := s.sessionRepo.RevokeOldestSessions(ctx, u.ID, cfg.MaxPerUser)
+		if err != nil {
+			return nil, err
+		}
+		if evicted > 0 {
+			s.server.Logger.Info().Str("user_id", u.ID.String()).Int("evicted", evicted).Msg("session limit reached, signed out oldest sessions")
+		}
+	}
+
+	return sess, nil
+}
+
+// Lifetime returns the token TTLs for the user's role and the session's client
+func (s *SessionService) Lifetime(u *user.User, clientID string) config.TokenLifetime {
+	role := ""
+	if u.UserType != nil {
+		role = *u.UserType
+	}
+
+	return s.server.Config.Sessions.Lifetime(role, clientID)
+}
+
+// Refresh slides the idle expiry of the user's session forward by the refresh TTL,
+// never past its absolute expiry
+func (s *SessionService) Refresh(ctx context.Context, u *user.User, sessionID uuid.UUID) (*session.Session, error) {
+	ctx, span := tracing.Start(ctx, "SessionService.Refresh")
+	defer span.End()
+
+	sess, err := s.sessionRepo.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !sess.Active(now) || sess.UserID != u.ID {
+		return nil, errs.NewUnauthorizedError("session has expired or been revoked", true)
+	}
+
+	expiresAt := earliest(now.Add(s.Lifetime(u, sess.ClientID).RefreshTTL), sess.AbsoluteExpiresAt)
+
+	extended, err := s.sessionRepo.ExtendSession(ctx, sess.ID, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if !extended {
+		return nil, errs.NewUnauthorizedError("session has expired or been revoked", true)
+	}
+
+	sess.ExpiresAt = expiresAt
+	sess.LastSeenAt = now
+
+	return sess, nil
+}
+
+// Reauthenticate records that the user just proved their identity again with the
+// given factors, replacing the session's auth time and level
+func (s *SessionService) Reauthenticate(ctx context.Context, u *user.User, sessionID uuid.UUID, factors []string) (*session.Session, error) {
+	sess, err := s.sessionRepo.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !sess.Active(now) || sess.UserID != u.ID {
+		return nil, errs.NewUnauthorizedError("session has expired or been revoked", true)
+	}
+
+	amr, acr := authenticationOf(factors)
+
+	updated, err := s.sessionRepo.UpdateSessionAuthentication(ctx, sess.ID, now, amr, acr)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, errs.NewUnauthorizedError("session has expired or been revoked", true)
+	}
+
+	sess.AuthTime = now
+	sess.AMR = amr
+	sess.ACR = acr
+	sess.LastSeenAt = now
+
+	return sess, nil
+}
+
+// Authenticate checks that a token's session still belongs to the user and is
+// active, and records the activity
+func (s *SessionService) Authenticate(ctx context.Context, userID, sessionID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "SessionService.Authenticate")
+	defer span.End()
+
+	sess, err := s.sessionRepo.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if !sess.Active(now) || sess.UserID != userID {
+		return errs.NewUnauthorizedError("session has expired or been revoked", true)
+	}
+
+	if now.Sub(sess.LastSeenAt) >= sessionTouchInterval {
+		if err := s.sessionRepo.TouchSession(ctx, sess.ID, now); err != nil {
+			s.server.Logger.Error().Err(err).Str("session_id", sess.ID.String()).Msg("failed to update session last seen time")
+		}
+	}
+
+	return nil
+}
+
+// GetSessions lists the user's active sessions. current, when set, is flagged in the response.
+func (s *SessionService) GetSessions(ctx context.Context, userID uuid.UUID, current *uuid.UUID) ([]session.SessionResponse, error) {
+	sessions, err := s.sessionRepo.GetActiveSessionsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]session.SessionResponse, 0, len(sessions))
+	for _, sess := range sessions {
+		responses = append(responses, session.SessionResponse{
+			ID:         sess.ID,
+			ClientID:   sess.ClientID,
+			DeviceName: sess.DeviceName,
+			IPAddress:  sess.IPAddress,
+			UserAgent:  sess.UserAgent,
+			Current:    current != nil && *current == sess.ID,
+			LastSeenAt: sess.LastSeenAt,
+			ExpiresAt:  earliest(sess.ExpiresAt, sess.AbsoluteExpiresAt),
+			CreatedAt:  sess.CreatedAt,
+		})
+	}
+
+	return responses, nil
+}
+
+func (s *SessionService) Revoke(ctx context.Context, userID, sessionID uuid.UUID) error {
+	revoked, err := s.sessionRepo.RevokeSession(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errs.NewNotFoundError("session not found", true, nil)
+	}
+
+	return nil
+}
+
+// RevokeAll signs the user out everywhere, except from the session given
+func (s *SessionService) RevokeAll(ctx context.Context, userID uuid.UUID, except *uuid.UUID) (*session.RevokeSessionsResponse, error) {
+	revoked, err := s.sessionRepo.RevokeUserSessions(ctx, userID, except)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session.RevokeSessionsResponse{Revoked: revoked}, nil
+}
+
+// authenticationOf maps login factors to amr values and an authentication level.
+// Two factors, or a passkey (always user verifying), make a multi-factor login.
+func authenticationOf(factors []string) ([]string, int) {
+	amr := make([]string, 0, len(factors))
+	for _, factor := range factors {
+		var ref string
+		switch factor {
+		case loginhistory.MethodPassword:
+			ref = session.AMRPassword
+		case loginhistory.MethodMagicLink:
+			ref = session.AMREmail
+		case loginhistory.MethodPasskey, mfa.MethodWebAuthn:
+			ref = session.AMRWebAuthn
+		case mfa.MethodTOTP, mfa.MethodRecoveryCode:
+			ref = session.AMROTP
+		default:
+			continue
+		}
+		if !slices.Contains(amr, ref) {
+			amr = append(amr, ref)
+		}
+	}
+
+	if len(amr) > 1 || slices.Contains(factors, loginhistory.MethodPasskey) {
+		return amr, session.LevelMultiFactor
+	}
+	return amr, session.LevelSingleFactor
+}
+
+func earliest(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+// describeUserAgent turns a user agent into a short name like "Firefox on Windows"
+// for clients that do not name themselves with X-Device-Name
+func describeUserAgent(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	var browser string
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(userAgent, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(userAgent, "Firefox/"), strings.Contains(userAgent, "FxiOS/"):
+		browser = "Firefox"
+	case strings.Contains(userAgent, "Chrome/"), strings.Contains(userAgent, "CriOS/"):
+		browser = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		browser = "Safari"
+	}
+
+	var os string
+	switch {
+	case strings.Contains(userAgent, "iPhone"):
+		os = "iOS"
+	case strings.Contains(userAgent, "iPad"):
+		os = "iPadOS"
+	case strings.Contains(userAgent, "Android"):
+		os = "Android"
+	case strings.Contains(userAgent, "Windows"):
+		os = "Windows"
+	case strings.Contains(userAgent, "Mac OS X"):
+		os = "macOS"
+	case strings.Contains(userAgent, "CrOS"):
+		os = "ChromeOS"
+	case strings.Contains(userAgent, "Linux"):
+		os = "Linux"
+	}
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	}
+
+	// Non-browser clients such as "curl/8.5.0" or "MyApp/2.1 (build 7)"
+	product, _, _ := strings.Cut(userAgent, " ")
+	name, _, _ := strings.Cut(product, "/")
+	return name
+}
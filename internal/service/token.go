@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/2SSK/jwt/internal/config"
+	utils "github.com/2SSK/jwt/internal/lib"
+	"github.com/2SSK/jwt/internal/model/token"
+	"github.com/2SSK/jwt/internal/model/user"
+	"github.com/2SSK/jwt/internal/repository"
+	"github.com/2SSK/jwt/internal/server"
+	"github.com/2SSK/jwt/internal/tracing"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidToken is returned for tokens that are malformed, forged, expired or unknown
+var ErrInvalidToken = errors.New("invalid token")
+
+// TokenService issues and parses access and refresh tokens in the format configured
+// for the client, see config.TokensConfig. Every token carries the id of its
+// session, and revoking a session takes effect at once whatever the format, as the
+// session is checked on every request.
+type TokenService struct {
+	server      *server.Server
+	tokenRepo   repository.TokenRepository
+	sessionRepo repository.SessionRepository
+	keys        *utils.SigningKeys
+	formats     map[string]tokenFormat
+}
+
+func NewTokenService(s *server.Server, tokenRepo repository.TokenRepository, sessionRepo repository.SessionRepository) (*TokenService, error) {
+	cfg := s.Config.Tokens
+
+	encrypter, err := utils.NewTokenEncrypter(s.Config.TokenEncryption)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := &utils.SigningKeys{}
+	if cfg.SigningKeysDir != "" {
+		if keys, err = utils.LoadSigningKeys(cfg.SigningKeysDir); err != nil {
+			return nil, err
+		}
+	}
+
+	formats := map[string]tokenFormat{
+		config.TokenFormatJWT:    &jwtFormat{secret: []byte(s.Config.Auth.SecretKey), keys: keys, encrypter: encrypter},
+		config.TokenFormatOpaque: newOpaqueFormat(cfg, tokenRepo),
+	}
+
+	// PASETO tokens are accepted whenever a key is configured, so tokens already
+	// issued keep working after a client is switched to another format
+	if cfg.PasetoPrivateKeyFile != "" {
+		if formats[config.TokenFormatPasetoPublic], err = newPasetoPublicFormat(cfg.PasetoPrivateKeyFile); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.PasetoLocalKey != "" {
+		if formats[config.TokenFormatPasetoLocal], err = newPasetoLocalFormat(cfg.PasetoLocalKey); err != nil {
+			return nil, err
+		}
+	}
+
+	if key := keys.Current(); key != nil {
+		s.Logger.Info().Int("keys", len(keys.All())).Str("kid", key.ID).Str("alg", key.Method.Alg()).Msg("loaded token signing keys")
+	}
+
+	return &TokenService{
+		server:      s,
+		tokenRepo:   tokenRepo,
+		sessionRepo: sessionRepo,
+		keys:        keys,
+		formats:     formats,
+	}, nil
+}
+
+// Issue returns a token carrying claims, which must include user_id, sid, typ and
+// exp, in the given format. The configured issuer and audience are added to them.
+func (s *TokenService) Issue(ctx context.Context, claims jwt.MapClaims, format string) (string, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.Issue")
+	defer span.End()
+
+	f, ok := s.formats[format]
+	if !ok {
+		return "", fmt.Errorf("token format %s is not configured", format)
+	}
+
+	cfg := s.server.Config.Tokens
+	if cfg.Issuer != "" {
+		claims["iss"] = cfg.Issuer
+	}
+	switch len(cfg.Audience) {
+	case 0:
+	case 1:
+		claims["aud"] = cfg.Audience[0]
+	default:
+		claims["aud"] = cfg.Audience
+	}
+
+	issued, err := f.issue(ctx, claims)
+	if err != nil {
+		return "", err
+	}
+
+	tokenType, _ := claims["typ"].(string)
+	s.server.Metrics.TokensIssued.WithLabelValues(tokenType, format).Inc()
+
+	return issued, nil
+}
+
+// JWKS returns the public keys JWTs are signed with, for services verifying them
+func (s *TokenService) JWKS() jose.JSONWebKeySet {
+	return s.keys.JWKS()
+}
+
+// Parse returns the claims of a valid, unexpired token of any format. It does not
+// check the token's session.
+func (s *TokenService) Parse(ctx context.Context, tokenString string) (jwt.MapClaims, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.Parse")
+	defer span.End()
+
+	f, ok := s.formats[formatOf(tokenString)]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	return f.parse(ctx, tokenString)
+}
+
+// Introspect reports whether a token is active (RFC 7662): valid, unexpired and
+// issued for a session that is still active
+func (s *TokenService) Introspect(ctx context.Context, payload *token.IntrospectPayload) (*token.IntrospectionResponse, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.Introspect")
+	defer span.End()
+
+	claims, err := s.Parse(ctx, payload.Token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			return &token.IntrospectionResponse{Active: false}, nil
+		}
+		return nil, err
+	}
+
+	userID, _ := claims["user_id"].(string)
+	sessionID, err := uuid.Parse(stringClaim(claims, "sid"))
+	if err != nil {
+		return &token.IntrospectionResponse{Active: false}, nil
+	}
+
+	sess, err := s.sessionRepo.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if sess == nil || !sess.Active(time.Now()) || sess.UserID.String() != userID {
+		return &token.IntrospectionResponse{Active: false}, nil
+	}
+
+	response := &token.IntrospectionResponse{
+		Active:    true,
+		Sub:       userID,
+		ClientID:  sess.ClientID,
+		SessionID: sess.ID.String(),
+		Typ:       stringClaim(claims, "typ"),
+		TokenType: user.TokenTypeBearer,
+		Exp:       int64Claim(claims, "exp"),
+		Iat:       int64Claim(claims, "iat"),
+		AuthTime:  int64Claim(claims, "auth_time"),
+		ACR:       stringClaim(claims, "acr"),
+	}
+	if sess.DPoPJKT != "" {
+		response.TokenType = user.TokenTypeDPoP
+	}
+	if amr, ok := claims["amr"].([]interface{}); ok {
+		for _, ref := range amr {
+			if ref, ok := ref.(string); ok {
+				response.AMR = append(response.AMR, ref)
+			}
+		}
+	}
+	if cnf, ok := claims["cnf"].(map[string]interface{}); ok {
+		response.Cnf = make(map[string]string, len(cnf))
+		for key, value := range cnf {
+			if value, ok := value.(string); ok {
+				response.Cnf[key] = value
+			}
+		}
+	}
+
+	return response, nil
+}
+
+// RunCleanup deletes expired opaque tokens until ctx is cancelled
+func (s *TokenService) RunCleanup(ctx context.Context) {
+	ticker := time.NewTicker(s.server.Config.Tokens.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := s.tokenRepo.DeleteExpiredOpaqueTokens(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.server.Logger.Error().Err(err).Msg("failed to delete expired opaque tokens")
+			}
+		} else if deleted > 0 {
+			s.server.Logger.Info().Int("deleted", deleted).Msg("deleted expired opaque tokens")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func stringClaim(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// int64Claim reads a numeric claim, which JSON decodes as float64
+func int64Claim(claims jwt.MapClaims, name string) int64 {
+	value, _ := claims[name].(float64)
+	return int64(value)
+}
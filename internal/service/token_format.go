@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/2SSK/jwt/internal/config"
+	utils "github.com/2SSK/jwt/internal/lib"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// tokenFormat issues and parses the tokens of one format. Issued claims always
+// include user_id, sid, typ and exp, with times as Unix seconds; parsed claims
+// decode like JSON, so times are float64 whatever the format.
+type tokenFormat interface {
+	issue(ctx context.Context, claims jwt.MapClaims) (string, error)
+	// parse returns ErrInvalidToken for malformed, forged, expired or unknown tokens
+	parse(ctx context.Context, token string) (jwt.MapClaims, error)
+}
+
+// formatOf tells the format of a token from its shape
+func formatOf(token string) string {
+	switch {
+	case strings.HasPrefix(token, utils.PasetoV4PublicHeader):
+		return config.TokenFormatPasetoPublic
+	case strings.HasPrefix(token, utils.PasetoV4LocalHeader):
+		return config.TokenFormatPasetoLocal
+	case !strings.Contains(token, "."):
+		// JWTs and JWEs are dot separated, opaque tokens are a single base64url string
+		return config.TokenFormatOpaque
+	default:
+		return config.TokenFormatJWT
+	}
+}
+
+// jwtFormat signs tokens with the newest signing key, or HS256 with the shared
+// secret when there is none, and wraps them in a JWE when token encryption is
+// enabled
+type jwtFormat struct {
+	secret    []byte
+	keys      *utils.SigningKeys
+	encrypter *utils.TokenEncrypter
+}
+
+func (f *jwtFormat) issue(_ context.Context, claims jwt.MapClaims) (string, error) {
+	var signed string
+	var err error
+	if key := f.keys.Current(); key != nil {
+		token := jwt.NewWithClaims(key.Method, claims)
+		token.Header["kid"] = key.ID
+		signed, err = token.SignedString(key.Key)
+	} else {
+		signed, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(f.secret)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return f.encrypter.Encrypt(signed)
+}
+
+func (f *jwtFormat) parse(_ context.Context, tokenString string) (jwt.MapClaims, error) {
+	// Encrypted tokens are unwrapped first, the signed token inside is verified as usual
+	signed, err := f.encrypter.Decrypt(tokenString)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// Tokens signed with the shared secret stay valid after keys are added, until
+	// they expire. Keys are typed, so a token can only be verified with a key of
+	// its algorithm's type.
+	parsed, err := jwt.Parse(signed, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+			return f.secret, nil
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, ok := f.keys.PublicKey(kid)
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+		return key, nil
+	})
+	if err != nil || !parsed.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// pasetoFormat issues PASETO v4 tokens, public (signed) or local (encrypted). The
+// claims are the JWT ones, except that exp, iat and nbf are RFC 3339 strings as
+// PASETO requires.
+type pasetoFormat struct {
+	seal func(payload []byte) (string, error)
+	open func(token string) ([]byte, error)
+}
+
+func newPasetoPublicFormat(privateKeyFile string) (*pasetoFormat, error) {
+	paseto, err := utils.NewPasetoV4Public(privateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pasetoFormat{
+		seal: func(payload []byte) (string, error) { return paseto.Sign(payload), nil },
+		open: paseto.Verify,
+	}, nil
+}
+
+func newPasetoLocalFormat(key string) (*pasetoFormat, error) {
+	paseto, err := utils.NewPasetoV4Local(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pasetoFormat{seal: paseto.Encrypt, open: paseto.Decrypt}, nil
+}
+
+// pasetoTimeClaims are the registered PASETO claims holding times
+var pasetoTimeClaims = []string{"exp", "iat", "nbf"}
+
+func (f *pasetoFormat) issue(_ context.Context, claims jwt.MapClaims) (string, error) {
+	payload := make(map[string]any, len(claims))
+	for name, value := range claims {
+		payload[name] = value
+	}
+	for _, name := range pasetoTimeClaims {
+		if seconds, ok := claims[name].(int64); ok {
+			payload[name] = time.Unix(seconds, 0).UTC().Format(time.RFC3339)
+		}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return f.seal(data)
+}
+
+func (f *pasetoFormat) parse(_ context.Context, token string) (jwt.MapClaims, error) {
+	data, err := f.open(token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims jwt.MapClaims
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	for _, name := range pasetoTimeClaims {
+		value, ok := claims[name]
+		if !ok {
+			continue
+		}
+		text, _ := value.(string)
+		t, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			return nil, ErrInvalidToken
+		}
+		claims[name] = float64(t.Unix())
+	}
+
+	now := float64(time.Now().Unix())
+	exp, ok := claims["exp"].(float64)
+	if !ok || exp <= now {
+		return nil, ErrInvalidToken
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && nbf > now {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/2SSK/jwt/internal/config"
+	utils "github.com/2SSK/jwt/internal/lib"
+	"github.com/2SSK/jwt/internal/model/token"
+	"github.com/2SSK/jwt/internal/repository"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// opaqueTokenBytes is the entropy of an opaque token
+const opaqueTokenBytes = 32
+
+// opaqueFormat issues random reference tokens. Their claims are stored under the
+// token's hash and cached in memory after the first lookup.
+type opaqueFormat struct {
+	config    *config.TokensConfig
+	tokenRepo repository.TokenRepository
+
+	mu    sync.Mutex
+	cache map[string]cachedClaims
+}
+
+type cachedClaims struct {
+	claims    jwt.MapClaims
+	expiresAt time.Time
+}
+
+func newOpaqueFormat(cfg *config.TokensConfig, tokenRepo repository.TokenRepository) *opaqueFormat {
+	return &opaqueFormat{
+		config:    cfg,
+		tokenRepo: tokenRepo,
+		cache:     make(map[string]cachedClaims),
+	}
+}
+
+func (f *opaqueFormat) issue(ctx context.Context, claims jwt.MapClaims) (string, error) {
+	opaque, err := utils.RandomToken(opaqueTokenBytes)
+	if err != nil {
+		return "", err
+	}
+
+	userID, _ := uuid.Parse(claims["user_id"].(string))
+	sessionID, _ := uuid.Parse(claims["sid"].(string))
+	exp := claims["exp"].(int64)
+
+	_, err = f.tokenRepo.CreateOpaqueToken(ctx, &token.OpaqueToken{
+		TokenHash: utils.HashToken(opaque),
+		TokenType: claims["typ"].(string),
+		UserID:    userID,
+		SessionID: sessionID,
+		Claims:    claims,
+		ExpiresAt: time.Unix(exp, 0),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return opaque, nil
+}
+
+func (f *opaqueFormat) parse(ctx context.Context, opaque string) (jwt.MapClaims, error) {
+	tokenHash := utils.HashToken(opaque)
+	now := time.Now()
+
+	if claims, ok := f.cached(tokenHash, now); ok {
+		return claims, nil
+	}
+
+	stored, err := f.tokenRepo.GetOpaqueTokenByHash(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || !now.Before(stored.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	claims := jwt.MapClaims(stored.Claims)
+	f.remember(tokenHash, claims, stored.ExpiresAt, now)
+
+	return claims, nil
+}
+
+func (f *opaqueFormat) cached(tokenHash string, now time.Time) (jwt.MapClaims, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entry, ok := f.cache[tokenHash]
+	if !ok || !now.Before(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.claims, true
+}
+
+// remember caches a token's claims for the cache TTL, never past the token's expiry.
+// A full cache is swept of expired entries first and left alone if that frees nothing.
+func (f *opaqueFormat) remember(tokenHash string, claims jwt.MapClaims, expiresAt, now time.Time) {
+	if f.config.CacheTTL == 0 || f.config.CacheSize == 0 {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.cache) >= f.config.CacheSize {
+		for hash, entry := range f.cache {
+			if !now.Before(entry.expiresAt) {
+				delete(f.cache, hash)
+			}
+		}
+		if len(f.cache) >= f.config.CacheSize {
+			return
+		}
+	}
+
+	f.cache[tokenHash] = cachedClaims{claims: claims, expiresAt: earliest(now.Add(f.config.CacheTTL), expiresAt)}
+}
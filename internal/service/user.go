@@ -3,40 +3,154 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/2SSK/jwt/internal/errs"
+	utils "github.com/2SSK/jwt/internal/lib"
+	"github.com/2SSK/jwt/internal/model/loginhistory"
+	"github.com/2SSK/jwt/internal/model/mfa"
+	"github.com/2SSK/jwt/internal/model/session"
 	"github.com/2SSK/jwt/internal/model/user"
+	"github.com/2SSK/jwt/internal/model/webhook"
 	"github.com/2SSK/jwt/internal/repository"
+	"github.com/2SSK/jwt/internal/server"
+	"github.com/2SSK/jwt/internal/tracing"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	MFAChallengeTTL         = 5 * time.Minute
+	MFAChallengeMaxAttempts = 5
+)
+
+// Token types, carried in the typ claim. Only refresh tokens are accepted by the
+// refresh endpoint and only access tokens everywhere else.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+var errPasswordMismatch = errors.New("password does not match")
+
 type UserService struct {
-	userRepo  repository.UserRepository
-	jwtSecret []byte
+	server       *server.Server
+	userRepo     repository.UserRepository
+	mfaRepo      repository.MFARepository
+	webauthnRepo repository.WebAuthnRepository
+	webhooks     *WebhookService
+	lockout      *LockoutService
+	loginHistory *LoginHistoryService
+	sessions     *SessionService
+	tokens       *TokenService
+	hasher       utils.PasswordHasher
+	policy       *utils.PasswordPolicy
+	// dummyHash is verified against when the account does not exist, so unknown
+	// emails take as long to reject as wrong passwords
+	dummyHash string
 }
 
-func NewUserService(userRepo repository.UserRepository, jwtSecret string) *UserService {
-	return &UserService{
-		userRepo:  userRepo,
-		jwtSecret: []byte(jwtSecret),
+func NewUserService(s *server.Server, repos *repository.Repositories, webhooks *WebhookService, lockout *LockoutService, loginHistory *LoginHistoryService, sessions *SessionService, tokens *TokenService) (*UserService, error) {
+	hasher := utils.NewPasswordHasher(s.Config.PasswordHashing)
+
+	dummyHash, err := hasher.Hash(uuid.NewString())
+	if err != nil {
+		return nil, err
 	}
+
+	policy, err := utils.NewPasswordPolicy(s.Config.PasswordPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserService{
+		server:       s,
+		userRepo:     repos.User,
+		mfaRepo:      repos.MFA,
+		webauthnRepo: repos.WebAuthn,
+		webhooks:     webhooks,
+		lockout:      lockout,
+		loginHistory: loginHistory,
+		sessions:     sessions,
+		tokens:       tokens,
+		hasher:       hasher,
+		policy:       policy,
+		dummyHash:    dummyHash,
+	}, nil
 }
 
 func (s *UserService) HashPassword(password string) (string, error) {
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return s.hasher.Hash(password)
+}
+
+func (s *UserService) VerifyPassword(hashedPassword, password string) error {
+	ok, err := s.hasher.Verify(hashedPassword, password)
 	if err != nil {
-		return "", err
+		return err
+	}
+	if !ok {
+		return errPasswordMismatch
 	}
-	return string(hashedBytes), nil
+	return nil
 }
 
-func (s *UserService) VerifyPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+// upgradePasswordHash re-hashes a just verified password when its stored hash uses an
+// outdated algorithm or parameters. Failures are logged and do not block the login.
+func (s *UserService) upgradePasswordHash(ctx context.Context, u *user.User, password string) {
+	if !s.hasher.NeedsRehash(*u.Password) {
+		return
+	}
+
+	hashedPassword, err := s.hasher.Hash(password)
+	if err == nil {
+		err = s.userRepo.UpdatePassword(ctx, u.ID, hashedPassword)
+	}
+	if err != nil {
+		s.server.Logger.Error().Err(err).Str("user_id", u.ID.String()).Msg("failed to upgrade password hash")
+		return
+	}
+
+	u.Password = &hashedPassword
 }
 
-func (s *UserService) SignUp(ctx context.Context, payload *user.AddUserPayload) (*user.SignUpResponse, error) {
+func (s *UserService) SignUp(ctx context.Context, payload *user.AddUserPayload, client user.ClientInfo) (*user.SignUpResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.SignUp")
+	defer span.End()
+
+	createdUser, err := s.CreateUser(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	sess, err := s.sessions.Create(ctx, createdUser, []string{loginhistory.MethodPassword}, client)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate tokens
+	tokens, err := s.generateTokens(ctx, createdUser, sess)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update user with tokens (optional, depending on design)
+	createdUser.Token = &tokens.AccessToken
+	createdUser.RefreshToken = &tokens.RefreshToken
+
+	return &user.SignUpResponse{
+		User:          userResponse(createdUser),
+		TokenResponse: *tokens,
+	}, nil
+}
+
+// CreateUser adds an account without signing it in, for signups and for admins
+// creating users
+func (s *UserService) CreateUser(ctx context.Context, payload *user.AddUserPayload) (*user.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.CreateUser")
+	defer span.End()
+
 	// Check if user already exists
 	existingUser, err := s.userRepo.GetUserByEmail(ctx, payload.Email)
 	if err != nil {
@@ -46,6 +160,10 @@ func (s *UserService) SignUp(ctx context.Context, payload *user.AddUserPayload)
 		return nil, errors.New("user with this email already exists")
 	}
 
+	if err := s.checkNewPassword(ctx, "password", payload.Password, nil, payload.Email, payload.FirstName, payload.LastName); err != nil {
+		return nil, err
+	}
+
 	// Hash password
 	hashedPassword, err := s.HashPassword(payload.Password)
 	if err != nil {
@@ -73,55 +191,132 @@ func (s *UserService) SignUp(ctx context.Context, payload *user.AddUserPayload)
 		return nil, err
 	}
 
-	// Generate tokens
-	accessToken, refreshToken, err := s.generateTokens(createdUser.ID)
-	if err != nil {
-		return nil, err
-	}
+	s.recordPasswordHistory(ctx, createdUser.ID, hashedPassword)
 
-	// Update user with tokens (optional, depending on design)
-	createdUser.Token = &accessToken
-	createdUser.RefreshToken = &refreshToken
+	s.webhooks.Publish(ctx, webhook.EventUserCreated, userResponse(createdUser))
 
-	response := &user.SignUpResponse{
-		User: user.UserResponse{
-			ID:        createdUser.ID,
-			FirstName: createdUser.FirstName,
-			LastName:  createdUser.LastName,
-			Email:     createdUser.Email,
-			Phone:     createdUser.Phone,
-			UserType:  createdUser.UserType,
-			CreatedAt: createdUser.CreatedAt,
-			UpdatedAt: createdUser.UpdatedAt,
-		},
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	}
+	return createdUser, nil
+}
 
-	return response, nil
+func userResponse(u *user.User) user.UserResponse {
+	return user.UserResponse{
+		ID:        u.ID,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Email:     u.Email,
+		Phone:     u.Phone,
+		UserType:  u.UserType,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+	}
 }
 
-func (s *UserService) Login(ctx context.Context, payload *user.LoginPayload) (*user.LoginResponse, error) {
+// Login checks the password. Users with a confirmed second factor get an MFA
+// challenge instead of tokens, to be completed via MFAService.Verify. Failures
+// are counted per account and per ip, see LockoutService.
+func (s *UserService) Login(ctx context.Context, payload *user.LoginPayload, client user.ClientInfo) (*user.LoginResponse, *user.MFAChallengeResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Login")
+	defer span.End()
+
 	// Get user by email
 	u, err := s.userRepo.GetUserByEmail(ctx, payload.Email)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	if u == nil {
-		return nil, errors.New("invalid credentials")
+
+	if err := s.lockout.Check(ctx, payload.Email, client.IPAddress); err != nil {
+		reason := loginhistory.FailureThrottled
+		var httpErr *errs.HTTPError
+		if errors.As(err, &httpErr) && httpErr.Code == errs.CodeAccountLocked {
+			reason = loginhistory.FailureAccountLocked
+		}
+		s.loginHistory.RecordFailure(ctx, userIDOf(u), &payload.Email, loginhistory.MethodPassword, reason, client)
+		return nil, nil, err
+	}
+
+	if u == nil || u.Password == nil {
+		_ = s.VerifyPassword(s.dummyHash, payload.Password)
+		return nil, nil, s.loginFailed(ctx, payload.Email, u, client)
 	}
 
 	// Verify password
 	if err := s.VerifyPassword(*u.Password, payload.Password); err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, nil, s.loginFailed(ctx, payload.Email, u, client)
+	}
+
+	s.upgradePasswordHash(ctx, u, payload.Password)
+
+	response, challenge, err := s.CompleteFirstFactor(ctx, u, loginhistory.MethodPassword, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// With a second factor pending the failures are only cleared once it is
+	// completed, so the password cannot be used to reset the count of MFA guesses
+	if response != nil {
+		if err := s.lockout.RecordSuccess(ctx, payload.Email); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return response, challenge, nil
+}
+
+func (s *UserService) loginFailed(ctx context.Context, email string, u *user.User, client user.ClientInfo) error {
+	s.loginHistory.RecordFailure(ctx, userIDOf(u), &email, loginhistory.MethodPassword, loginhistory.FailureInvalidCredentials, client)
+
+	if err := s.lockout.RecordFailure(ctx, email, client.IPAddress, u); err != nil {
+		return err
+	}
+	return errors.New("invalid credentials")
+}
+
+// UnlockUser lifts a lockout caused by failed logins
+func (s *UserService) UnlockUser(ctx context.Context, userID uuid.UUID) error {
+	return s.lockout.Unlock(ctx, userID)
+}
+
+// CompleteFirstFactor finishes a login whose first factor has been verified. Users
+// with a second factor enrolled get an MFA challenge, everyone else gets tokens.
+func (s *UserService) CompleteFirstFactor(ctx context.Context, u *user.User, method string, client user.ClientInfo) (*user.LoginResponse, *user.MFAChallengeResponse, error) {
+	// Require a second factor when one is enrolled
+	methods, err := s.mfaMethods(ctx, u.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(methods) > 0 {
+		challenge, err := s.createMFAChallenge(ctx, u.ID, method, methods)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, challenge, nil
+	}
+
+	response, err := s.NewLoginResponse(ctx, u, []string{method}, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return response, nil, nil
+}
+
+// NewLoginResponse starts a session for a fully authenticated user, issues its
+// tokens and records the login. factors lists the login methods in the order they
+// were completed; the last one is recorded in the login history.
+func (s *UserService) NewLoginResponse(ctx context.Context, u *user.User, factors []string, client user.ClientInfo) (*user.LoginResponse, error) {
+	sess, err := s.sessions.Create(ctx, u, factors, client)
+	if err != nil {
+		return nil, err
 	}
 
 	// Generate tokens
-	accessToken, refreshToken, err := s.generateTokens(u.ID)
+	tokens, err := s.generateTokens(ctx, u, sess)
 	if err != nil {
 		return nil, err
 	}
 
+	s.loginHistory.RecordSuccess(ctx, u, factors[len(factors)-1], client)
+
 	response := &user.LoginResponse{
 		User: user.UserResponse{
 			ID:        u.ID,
@@ -133,15 +328,222 @@ func (s *UserService) Login(ctx context.Context, payload *user.LoginPayload) (*u
 			CreatedAt: u.CreatedAt,
 			UpdatedAt: u.UpdatedAt,
 		},
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		TokenResponse: *tokens,
 	}
 
 	return response, nil
 }
 
+// mfaMethods lists the second factors the user has enrolled
+func (s *UserService) mfaMethods(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	var methods []string
+
+	totp, err := s.mfaRepo.GetTOTPByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if totp.Confirmed() {
+		methods = append(methods, mfa.MethodTOTP, mfa.MethodRecoveryCode)
+	}
+
+	passkeys, err := s.webauthnRepo.CountCredentialsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if passkeys > 0 {
+		methods = append(methods, mfa.MethodWebAuthn)
+	}
+
+	return methods, nil
+}
+
+func (s *UserService) createMFAChallenge(ctx context.Context, userID uuid.UUID, firstFactor string, methods []string) (*user.MFAChallengeResponse, error) {
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.mfaRepo.CreateChallenge(ctx, &mfa.Challenge{
+		UserID:      userID,
+		FirstFactor: firstFactor,
+		TokenHash:   utils.HashToken(token),
+		ExpiresAt:   time.Now().Add(MFAChallengeTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user.MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		Methods:     methods,
+		ExpiresIn:   int(MFAChallengeTTL.Seconds()),
+	}, nil
+}
+
+// Logout signs the user out of the session their token was issued for
+func (s *UserService) Logout(ctx context.Context, userID, sessionID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "UserService.Logout")
+	defer span.End()
+
+	return s.sessions.Revoke(ctx, userID, sessionID)
+}
+
+// ChangePassword lets a signed in user replace their password after confirming the
+// current one. Their other sessions are signed out.
+func (s *UserService) ChangePassword(ctx context.Context, userID, sessionID uuid.UUID, payload *user.ChangePasswordPayload) error {
+	ctx, span := tracing.Start(ctx, "UserService.ChangePassword")
+	defer span.End()
+
+	u, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return errs.NewNotFoundError("user not found", true, nil)
+	}
+
+	if u.Password == nil || s.VerifyPassword(*u.Password, payload.CurrentPassword) != nil {
+		return errs.NewBadRequestError("current password is incorrect", true, nil, []errs.FieldError{
+			{Field: "currentPassword", Error: "is incorrect"},
+		}, nil)
+	}
+
+	if err := s.checkNewPassword(ctx, "newPassword", payload.NewPassword, u, personalInfo(u)...); err != nil {
+		return err
+	}
+
+	if err := s.setPassword(ctx, u, payload.NewPassword); err != nil {
+		return err
+	}
+
+	_, err = s.sessions.RevokeAll(ctx, u.ID, &sessionID)
+	return err
+}
+
+// ResetPassword sets a user's password on an administrator's behalf and signs
+// them out everywhere
+func (s *UserService) ResetPassword(ctx context.Context, userID uuid.UUID, payload *user.ResetPasswordPayload) error {
+	ctx, span := tracing.Start(ctx, "UserService.ResetPassword")
+	defer span.End()
+
+	u, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return errs.NewNotFoundError("user not found", true, nil)
+	}
+
+	if err := s.checkNewPassword(ctx, "newPassword", payload.NewPassword, u, personalInfo(u)...); err != nil {
+		return err
+	}
+
+	if err := s.setPassword(ctx, u, payload.NewPassword); err != nil {
+		return err
+	}
+
+	_, err = s.sessions.RevokeAll(ctx, u.ID, nil)
+	return err
+}
+
+// checkNewPassword applies the password policy and, for existing users, the reuse
+// check against recent passwords. Violations are reported as field errors on field.
+func (s *UserService) checkNewPassword(ctx context.Context, field, password string, u *user.User, personal ...string) error {
+	violations, err := s.policy.Check(password, personal...)
+	if err != nil {
+		return err
+	}
+
+	historySize := s.server.Config.PasswordPolicy.HistorySize
+	if u != nil && historySize > 0 {
+		reused, err := s.passwordReused(ctx, u, password, historySize)
+		if err != nil {
+			return err
+		}
+		if reused {
+			violations = append(violations, fmt.Sprintf("must not match any of your last %d passwords", historySize))
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	fieldErrors := make([]errs.FieldError, 0, len(violations))
+	for _, violation := range violations {
+		fieldErrors = append(fieldErrors, errs.FieldError{Field: field, Error: violation})
+	}
+
+	code := "PASSWORD_POLICY_VIOLATION"
+	return errs.NewBadRequestError("password does not meet the requirements", true, &code, fieldErrors, nil)
+}
+
+func (s *UserService) passwordReused(ctx context.Context, u *user.User, password string, historySize int) (bool, error) {
+	previous, err := s.userRepo.GetPasswordHistory(ctx, u.ID, historySize)
+	if err != nil {
+		return false, err
+	}
+	// Accounts created before history was kept only have their current hash
+	if u.Password != nil {
+		previous = append(previous, *u.Password)
+	}
+
+	for _, hash := range previous {
+		if s.VerifyPassword(hash, password) == nil {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (s *UserService) setPassword(ctx context.Context, u *user.User, password string) error {
+	hashedPassword, err := s.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, u.ID, hashedPassword); err != nil {
+		return err
+	}
+
+	s.recordPasswordHistory(ctx, u.ID, hashedPassword)
+
+	return nil
+}
+
+// recordPasswordHistory is best effort, a failure only weakens the reuse check
+func (s *UserService) recordPasswordHistory(ctx context.Context, userID uuid.UUID, hashedPassword string) {
+	historySize := s.server.Config.PasswordPolicy.HistorySize
+	if historySize == 0 {
+		return
+	}
+
+	if err := s.userRepo.AddPasswordHistory(ctx, userID, hashedPassword, historySize); err != nil {
+		s.server.Logger.Error().Err(err).Str("user_id", userID.String()).Msg("failed to record password history")
+	}
+}
+
+func userIDOf(u *user.User) *uuid.UUID {
+	if u == nil {
+		return nil
+	}
+	return &u.ID
+}
+
+func personalInfo(u *user.User) []string {
+	var values []string
+	for _, v := range []*string{u.Email, u.FirstName, u.LastName} {
+		if v != nil {
+			values = append(values, *v)
+		}
+	}
+	return values
+}
+
 func (s *UserService) GetUsers(ctx context.Context, limit, offset int) ([]*user.UserResponse, error) {
-	users, err := s.userRepo.GetUsers(ctx, limit, offset)
+	users, err := s.userRepo.GetUsersFromReplica(ctx, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -207,7 +609,7 @@ func (s *UserService) UpdateUser(ctx context.Context, id uuid.UUID, payload *use
 	}
 
 	// Return updated response
-	return &user.UserResponse{
+	response := &user.UserResponse{
 		ID:        existing.ID,
 		FirstName: existing.FirstName,
 		LastName:  existing.LastName,
@@ -216,7 +618,11 @@ func (s *UserService) UpdateUser(ctx context.Context, id uuid.UUID, payload *use
 		UserType:  existing.UserType,
 		CreatedAt: existing.CreatedAt,
 		UpdatedAt: existing.UpdatedAt,
-	}, nil
+	}
+
+	s.webhooks.Publish(ctx, webhook.EventUserUpdated, response)
+
+	return response, nil
 }
 
 func (s *UserService) DeleteUser(ctx context.Context, id uuid.UUID) error {
@@ -230,15 +636,47 @@ func (s *UserService) DeleteUser(ctx context.Context, id uuid.UUID) error {
 	}
 
 	// Delete
-	return s.userRepo.DeleteUser(ctx, id)
+	if err := s.userRepo.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+
+	s.webhooks.Publish(ctx, webhook.EventUserDeleted, user.UserResponse{
+		ID:        existing.ID,
+		FirstName: existing.FirstName,
+		LastName:  existing.LastName,
+		Email:     existing.Email,
+		Phone:     existing.Phone,
+		UserType:  existing.UserType,
+		CreatedAt: existing.CreatedAt,
+		UpdatedAt: existing.UpdatedAt,
+	})
+
+	return nil
 }
 
-func (s *UserService) GenerateTokens(ctx context.Context, userID uuid.UUID) (accessToken, refreshToken string, err error) {
-	return s.generateTokens(userID)
+// RefreshTokens issues new tokens for an existing session and extends its idle expiry
+func (s *UserService) RefreshTokens(ctx context.Context, userID, sessionID uuid.UUID) (*user.TokenResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.RefreshTokens")
+	defer span.End()
+
+	u, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errs.NewUnauthorizedError("invalid authentication", true)
+	}
+
+	sess, err := s.sessions.Refresh(ctx, u, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.generateTokens(ctx, u, sess)
 }
 
 func (s *UserService) GetUserByID(ctx context.Context, id uuid.UUID) (*user.UserResponse, error) {
-	u, err := s.userRepo.GetUserByID(ctx, id)
+	u, err := s.userRepo.GetUserByIDFromReplica(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -258,30 +696,90 @@ func (s *UserService) GetUserByID(ctx context.Context, id uuid.UUID) (*user.User
 	}, nil
 }
 
-func (s *UserService) generateTokens(userID uuid.UUID) (accessToken, refreshToken string, err error) {
+// ReauthenticateSession upgrades the user's current session after they proved their
+// identity again with the given factors, and issues tokens carrying the new auth
+// time and level
+func (s *UserService) ReauthenticateSession(ctx context.Context, u *user.User, sessionID uuid.UUID, factors []string) (*user.TokenResponse, error) {
+	sess, err := s.sessions.Reauthenticate(ctx, u, sessionID, factors)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.generateTokens(ctx, u, sess)
+}
+
+// generateTokens issues the access and refresh tokens for a session, in the format
+// configured for its client. Lifetimes come from the session config; neither token
+// outlives the session. Tokens of a session bound to a DPoP key or a client
+// certificate carry its thumbprint in cnf.
+func (s *UserService) generateTokens(ctx context.Context, u *user.User, sess *session.Session) (*user.TokenResponse, error) {
+	format := s.server.Config.Tokens.FormatFor(sess.ClientID)
+	lifetime := s.sessions.Lifetime(u, sess.ClientID)
+	now := time.Now()
+
 	// Access token (short-lived)
 	accessClaims := jwt.MapClaims{
-		"user_id": userID.String(),
-		"exp":     time.Now().Add(15 * time.Minute).Unix(),
-		"iat":     time.Now().Unix(),
-	}
-	accessTokenObj := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessToken, err = accessTokenObj.SignedString(s.jwtSecret)
+		"user_id":   u.ID.String(),
+		"sid":       sess.ID.String(),
+		"typ":       TokenTypeAccess,
+		"auth_time": sess.AuthTime.Unix(),
+		"amr":       sess.AMR,
+		"acr":       strconv.Itoa(sess.ACR),
+		"exp":       earliest(now.Add(lifetime.AccessTTL), sess.ExpiresAt).Unix(),
+		"iat":       now.Unix(),
+	}
+	tokenType := user.TokenTypeBearer
+	if sess.DPoPJKT != "" {
+		tokenType = user.TokenTypeDPoP
+	}
+	cnf := confirmationOf(sess)
+	if cnf != nil {
+		accessClaims["cnf"] = cnf
+	}
+	accessToken, err := s.tokens.Issue(ctx, accessClaims, format)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
-	// Refresh token (long-lived)
+	// Refresh token (long-lived), valid until the session's idle expiry
 	refreshClaims := jwt.MapClaims{
-		"user_id": userID.String(),
-		"exp":     time.Now().Add(7 * 24 * time.Hour).Unix(),
-		"iat":     time.Now().Unix(),
-	}
-	refreshTokenObj := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshToken, err = refreshTokenObj.SignedString(s.jwtSecret)
+		"user_id":   u.ID.String(),
+		"sid":       sess.ID.String(),
+		"typ":       TokenTypeRefresh,
+		"auth_time": sess.AuthTime.Unix(),
+		"amr":       sess.AMR,
+		"acr":       strconv.Itoa(sess.ACR),
+		"exp":       sess.ExpiresAt.Unix(),
+		"iat":       now.Unix(),
+	}
+	if cnf != nil {
+		refreshClaims["cnf"] = cnf
+	}
+	refreshToken, err := s.tokens.Issue(ctx, refreshClaims, format)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
-	return accessToken, refreshToken, nil
+	return &user.TokenResponse{
+		AccessToken:           accessToken,
+		TokenType:             tokenType,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: sess.ExpiresAt,
+	}, nil
+}
+
+// confirmationOf builds the cnf claim (RFC 7800) binding a session's tokens to the
+// DPoP key and client certificate it was created with, or nil for bearer tokens
+func confirmationOf(sess *session.Session) map[string]string {
+	cnf := map[string]string{}
+	if sess.DPoPJKT != "" {
+		cnf["jkt"] = sess.DPoPJKT
+	}
+	if sess.CertThumbprint != "" {
+		cnf["x5t#S256"] = sess.CertThumbprint
+	}
+	if len(cnf) == 0 {
+		return nil
+	}
+	return cnf
 }
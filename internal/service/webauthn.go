@@ -0,0 +1,513 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/2SSK/jwt/internal/errs"
+	"github.com/2SSK/jwt/internal/model/loginhistory"
+	"github.com/2SSK/jwt/internal/model/mfa"
+	"github.com/2SSK/jwt/internal/model/user"
+	"github.com/2SSK/jwt/internal/model/webauthn"
+	"github.com/2SSK/jwt/internal/repository"
+	"github.com/2SSK/jwt/internal/server"
+	"github.com/2SSK/jwt/internal/tracing"
+	"github.com/go-webauthn/webauthn/protocol"
+	wa "github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+// webauthnUser adapts a user and their stored credentials to the library's User interface.
+// The user handle is the 16 byte user id.
+type webauthnUser struct {
+	user        *user.User
+	credentials []*webauthn.Credential
+}
+
+func (u *webauthnUser) WebAuthnID() []byte {
+	id := u.user.ID
+	return id[:]
+}
+
+func (u *webauthnUser) WebAuthnName() string {
+	if u.user.Email != nil {
+		return *u.user.Email
+	}
+	return u.user.ID.String()
+}
+
+func (u *webauthnUser) WebAuthnDisplayName() string {
+	if u.user.FirstName != nil && u.user.LastName != nil {
+		return *u.user.FirstName + " " + *u.user.LastName
+	}
+	return u.WebAuthnName()
+}
+
+func (u *webauthnUser) WebAuthnCredentials() []wa.Credential {
+	credentials := make([]wa.Credential, 0, len(u.credentials))
+	for _, c := range u.credentials {
+		transports := make([]protocol.AuthenticatorTransport, 0, len(c.Transports))
+		for _, t := range c.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(t))
+		}
+
+		credentials = append(credentials, wa.Credential{
+			ID:              c.CredentialID,
+			PublicKey:       c.PublicKey,
+			AttestationType: c.AttestationType,
+			Transport:       transports,
+			Flags: wa.CredentialFlags{
+				UserPresent:    true,
+				UserVerified:   c.UserVerified,
+				BackupEligible: c.BackupEligible,
+				BackupState:    c.BackupState,
+			},
+			Authenticator: wa.Authenticator{
+				AAGUID:       c.AAGUID,
+				SignCount:    uint32(c.SignCount),
+				CloneWarning: c.CloneWarning,
+			},
+		})
+	}
+	return credentials
+}
+
+// credential finds the stored record matching a credential returned by the library
+func (u *webauthnUser) credential(id []byte) *webauthn.Credential {
+	for _, c := range u.credentials {
+		if string(c.CredentialID) == string(id) {
+			return c
+		}
+	}
+	return nil
+}
+
+type WebAuthnService struct {
+	server       *server.Server
+	webauthn     *wa.WebAuthn
+	userRepo     repository.UserRepository
+	webauthnRepo repository.WebAuthnRepository
+	mfaService   *MFAService
+	userService  *UserService
+}
+
+func NewWebAuthnService(s *server.Server, repos *repository.Repositories, mfaService *MFAService, userService *UserService) (*WebAuthnService, error) {
+	cfg := s.Config.WebAuthn
+
+	relyingParty, err := wa.New(&wa.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPDisplayName,
+		RPOrigins:     cfg.RPOrigins,
+		Timeouts: wa.TimeoutsConfig{
+			Login:        wa.TimeoutConfig{Enforce: true, Timeout: cfg.Timeout, TimeoutUVD: cfg.Timeout},
+			Registration: wa.TimeoutConfig{Enforce: true, Timeout: cfg.Timeout, TimeoutUVD: cfg.Timeout},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnService{
+		server:       s,
+		webauthn:     relyingParty,
+		userRepo:     repos.User,
+		webauthnRepo: repos.WebAuthn,
+		mfaService:   mfaService,
+		userService:  userService,
+	}, nil
+}
+
+// BeginRegistration starts adding a passkey to the signed in user's account
+func (s *WebAuthnService) BeginRegistration(ctx context.Context, userID uuid.UUID) (*webauthn.CeremonyResponse, error) {
+	waUser, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if waUser == nil {
+		return nil, errs.NewNotFoundError("user not found", true, nil)
+	}
+
+	options, session, err := s.webauthn.BeginRegistration(waUser,
+		wa.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+		wa.WithExclusions(wa.Credentials(waUser.WebAuthnCredentials()).CredentialDescriptors()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.storeSession(ctx, &userID, webauthn.CeremonyRegistration, session, options)
+}
+
+func (s *WebAuthnService) FinishRegistration(ctx context.Context, userID uuid.UUID, payload *webauthn.FinishRegistrationPayload) (*webauthn.CredentialResponse, error) {
+	session, err := s.consumeSession(ctx, payload.SessionID, webauthn.CeremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if string(session.UserID) != string(userID[:]) {
+		return nil, errs.NewBadRequestError("invalid or expired WebAuthn session", true, nil, nil, nil)
+	}
+
+	waUser, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if waUser == nil {
+		return nil, errs.NewNotFoundError("user not found", true, nil)
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(payload.Credential)
+	if err != nil {
+		return nil, errs.NewBadRequestError("malformed WebAuthn credential", true, nil, nil, nil)
+	}
+
+	credential, err := s.webauthn.CreateCredential(waUser, *session, parsed)
+	if err != nil {
+		return nil, errs.NewBadRequestError("WebAuthn registration failed: "+protocolErrorMessage(err), true, nil, nil, nil)
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, t := range credential.Transport {
+		transports = append(transports, string(t))
+	}
+
+	created, err := s.webauthnRepo.CreateCredential(ctx, &webauthn.Credential{
+		UserID:          userID,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       int64(credential.Authenticator.SignCount),
+		UserVerified:    credential.Flags.UserVerified,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		Name:            payload.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := toCredentialResponse(created)
+	return &response, nil
+}
+
+// BeginLogin starts a passwordless login with a discoverable credential
+func (s *WebAuthnService) BeginLogin(ctx context.Context) (*webauthn.CeremonyResponse, error) {
+	options, session, err := s.webauthn.BeginDiscoverableLogin(
+		wa.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.storeSession(ctx, nil, webauthn.CeremonyLogin, session, options)
+}
+
+// FinishLogin verifies a passwordless assertion. A user verifying passkey is
+// multi-factor by itself, so tokens are issued without a further challenge.
+func (s *WebAuthnService) FinishLogin(ctx context.Context, payload *webauthn.FinishLoginPayload, client user.ClientInfo) (*user.LoginResponse, error) {
+	ctx, span := tracing.Start(ctx, "WebAuthnService.FinishLogin")
+	defer span.End()
+
+	session, err := s.consumeSession(ctx, payload.SessionID, webauthn.CeremonyLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(payload.Credential)
+	if err != nil {
+		return nil, errs.NewBadRequestError("malformed WebAuthn credential", true, nil, nil, nil)
+	}
+
+	var waUser *webauthnUser
+	handler := func(rawID, userHandle []byte) (wa.User, error) {
+		userID, err := uuid.FromBytes(userHandle)
+		if err != nil {
+			return nil, err
+		}
+		waUser, err = s.loadUser(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if waUser == nil {
+			return nil, errs.NewUnauthorizedError("unknown credential", true)
+		}
+		return waUser, nil
+	}
+
+	_, credential, err := s.webauthn.ValidatePasskeyLogin(handler, *session, parsed)
+	if err != nil || waUser == nil {
+		return nil, errs.NewUnauthorizedError("passkey authentication failed", true)
+	}
+
+	if err := s.recordUse(ctx, waUser, credential); err != nil {
+		return nil, err
+	}
+
+	return s.userService.NewLoginResponse(ctx, waUser.user, []string{loginhistory.MethodPasskey}, client)
+}
+
+// BeginMFA starts an assertion against the credentials of the user behind an MFA challenge
+func (s *WebAuthnService) BeginMFA(ctx context.Context, payload *webauthn.BeginMFAPayload) (*webauthn.CeremonyResponse, error) {
+	challenge, err := s.mfaService.ResolveChallenge(ctx, payload.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	waUser, err := s.loadUser(ctx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if waUser == nil || len(waUser.credentials) == 0 {
+		return nil, errs.NewBadRequestError("no passkeys registered", true, nil, nil, nil)
+	}
+
+	options, session, err := s.webauthn.BeginLogin(waUser)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.storeSession(ctx, &challenge.UserID, webauthn.CeremonyMFA, session, options)
+}
+
+// FinishMFA completes an MFA challenge with a passkey assertion and issues tokens
+func (s *WebAuthnService) FinishMFA(ctx context.Context, payload *webauthn.FinishMFAPayload, client user.ClientInfo) (*user.LoginResponse, error) {
+	ctx, span := tracing.Start(ctx, "WebAuthnService.FinishMFA")
+	defer span.End()
+
+	challenge, err := s.mfaService.AttemptChallenge(ctx, payload.MFAToken, mfa.MethodWebAuthn, client)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.consumeSession(ctx, payload.SessionID, webauthn.CeremonyMFA)
+	if err != nil {
+		return nil, err
+	}
+	if string(session.UserID) != string(challenge.UserID[:]) {
+		return nil, errs.NewBadRequestError("invalid or expired WebAuthn session", true, nil, nil, nil)
+	}
+
+	waUser, err := s.loadUser(ctx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if waUser == nil {
+		return nil, errs.NewUnauthorizedError("invalid or expired MFA token", true)
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(payload.Credential)
+	if err != nil {
+		return nil, errs.NewBadRequestError("malformed WebAuthn credential", true, nil, nil, nil)
+	}
+
+	credential, err := s.webauthn.ValidateLogin(waUser, *session, parsed)
+	if err != nil {
+		return nil, s.mfaService.FailChallenge(ctx, challenge, mfa.MethodWebAuthn, client)
+	}
+
+	if err := s.recordUse(ctx, waUser, credential); err != nil {
+		return nil, err
+	}
+
+	return s.mfaService.CompleteChallenge(ctx, challenge, mfa.MethodWebAuthn, client)
+}
+
+// BeginReauthentication starts a user verifying assertion against the signed in
+// user's passkeys, to upgrade their current session
+func (s *WebAuthnService) BeginReauthentication(ctx context.Context, userID uuid.UUID) (*webauthn.CeremonyResponse, error) {
+	waUser, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if waUser == nil || len(waUser.credentials) == 0 {
+		return nil, errs.NewBadRequestError("no passkeys registered", true, nil, nil, nil)
+	}
+
+	options, session, err := s.webauthn.BeginLogin(waUser, wa.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.storeSession(ctx, &userID, webauthn.CeremonyReauth, session, options)
+}
+
+// FinishReauthentication verifies the assertion and upgrades the current session
+// to multi-factor
+func (s *WebAuthnService) FinishReauthentication(ctx context.Context, userID, sessionID uuid.UUID, payload *webauthn.FinishLoginPayload) (*user.TokenResponse, error) {
+	session, err := s.consumeSession(ctx, payload.SessionID, webauthn.CeremonyReauth)
+	if err != nil {
+		return nil, err
+	}
+	if string(session.UserID) != string(userID[:]) {
+		return nil, errs.NewBadRequestError("invalid or expired WebAuthn session", true, nil, nil, nil)
+	}
+
+	waUser, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if waUser == nil {
+		return nil, errs.NewUnauthorizedError("invalid authentication", true)
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(payload.Credential)
+	if err != nil {
+		return nil, errs.NewBadRequestError("malformed WebAuthn credential", true, nil, nil, nil)
+	}
+
+	credential, err := s.webauthn.ValidateLogin(waUser, *session, parsed)
+	if err != nil {
+		return nil, errs.NewUnauthorizedError("passkey authentication failed", true)
+	}
+
+	if err := s.recordUse(ctx, waUser, credential); err != nil {
+		return nil, err
+	}
+
+	return s.userService.ReauthenticateSession(ctx, waUser.user, sessionID, []string{loginhistory.MethodPasskey})
+}
+
+func (s *WebAuthnService) GetCredentials(ctx context.Context, userID uuid.UUID) ([]webauthn.CredentialResponse, error) {
+	credentials, err := s.webauthnRepo.GetCredentialsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]webauthn.CredentialResponse, 0, len(credentials))
+	for _, c := range credentials {
+		responses = append(responses, toCredentialResponse(c))
+	}
+
+	return responses, nil
+}
+
+func (s *WebAuthnService) RenameCredential(ctx context.Context, userID, id uuid.UUID, name string) (*webauthn.CredentialResponse, error) {
+	credential, err := s.webauthnRepo.RenameCredential(ctx, userID, id, name)
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil {
+		return nil, errs.NewNotFoundError("passkey not found", true, nil)
+	}
+
+	response := toCredentialResponse(credential)
+	return &response, nil
+}
+
+func (s *WebAuthnService) DeleteCredential(ctx context.Context, userID, id uuid.UUID) error {
+	deleted, err := s.webauthnRepo.DeleteCredential(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errs.NewNotFoundError("passkey not found", true, nil)
+	}
+	return nil
+}
+
+// recordUse persists the new sign count. A counter that did not advance means the
+// authenticator may have been cloned, so the credential is flagged and refused from then on.
+func (s *WebAuthnService) recordUse(ctx context.Context, waUser *webauthnUser, credential *wa.Credential) error {
+	stored := waUser.credential(credential.ID)
+	if stored == nil {
+		return errs.NewUnauthorizedError("unknown credential", true)
+	}
+
+	stored.SignCount = int64(credential.Authenticator.SignCount)
+	stored.CloneWarning = stored.CloneWarning || credential.Authenticator.CloneWarning
+	stored.BackupState = credential.Flags.BackupState
+
+	if err := s.webauthnRepo.RecordCredentialUse(ctx, stored); err != nil {
+		return err
+	}
+
+	if stored.CloneWarning {
+		s.server.Logger.Warn().
+			Str("user_id", waUser.user.ID.String()).
+			Str("credential_id", stored.ID.String()).
+			Msg("webauthn sign count did not increase, possible cloned authenticator")
+		return errs.NewUnauthorizedError("passkey authentication failed", true)
+	}
+
+	return nil
+}
+
+func (s *WebAuthnService) loadUser(ctx context.Context, userID uuid.UUID) (*webauthnUser, error) {
+	u, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, nil
+	}
+
+	credentials, err := s.webauthnRepo.GetCredentialsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webauthnUser{user: u, credentials: credentials}, nil
+}
+
+func (s *WebAuthnService) storeSession(ctx context.Context, userID *uuid.UUID, ceremony string, data *wa.SessionData, options any) (*webauthn.CeremonyResponse, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.webauthnRepo.CreateSession(ctx, &webauthn.Session{
+		UserID:    userID,
+		Ceremony:  ceremony,
+		Data:      raw,
+		ExpiresAt: time.Now().Add(s.server.Config.WebAuthn.Timeout),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &webauthn.CeremonyResponse{
+		SessionID: session.ID,
+		Options:   options,
+	}, nil
+}
+
+func (s *WebAuthnService) consumeSession(ctx context.Context, id uuid.UUID, ceremony string) (*wa.SessionData, error) {
+	session, err := s.webauthnRepo.ConsumeSession(ctx, id, ceremony)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || time.Now().After(session.ExpiresAt) {
+		return nil, errs.NewBadRequestError("invalid or expired WebAuthn session", true, nil, nil, nil)
+	}
+
+	var data wa.SessionData
+	if err := json.Unmarshal(session.Data, &data); err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// protocolErrorMessage prefers the library's client-safe detail over its generic message
+func protocolErrorMessage(err error) string {
+	if perr, ok := err.(*protocol.Error); ok && perr.Details != "" {
+		return perr.Details
+	}
+	return err.Error()
+}
+
+func toCredentialResponse(c *webauthn.Credential) webauthn.CredentialResponse {
+	return webauthn.CredentialResponse{
+		ID:             c.ID,
+		Name:           c.Name,
+		Transports:     c.Transports,
+		SignCount:      c.SignCount,
+		CloneWarning:   c.CloneWarning,
+		BackupEligible: c.BackupEligible,
+		BackupState:    c.BackupState,
+		LastUsedAt:     c.LastUsedAt,
+		CreatedAt:      c.CreatedAt,
+	}
+}
@@ -0,0 +1,500 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	mathrand "math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/2SSK/jwt/internal/errs"
+	"github.com/2SSK/jwt/internal/model/webhook"
+	"github.com/2SSK/jwt/internal/repository"
+	"github.com/2SSK/jwt/internal/server"
+	"github.com/2SSK/jwt/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	WebhookEventIDHeader    = "X-Webhook-Event-Id"
+	WebhookEventTypeHeader  = "X-Webhook-Event"
+	WebhookDeliveryIDHeader = "X-Webhook-Delivery-Id"
+	WebhookTimestampHeader  = "X-Webhook-Timestamp"
+	WebhookSignatureHeader  = "X-Webhook-Signature"
+
+	webhookSecretPrefix      = "whsec_"
+	webhookMaxResponseBody   = 4096
+	webhookDisabledByAdmin   = "disabled by admin"
+	webhookDisabledByFailure = "disabled after repeated delivery failures"
+)
+
+type WebhookService struct {
+	server      *server.Server
+	webhookRepo repository.WebhookRepository
+	client      *http.Client
+}
+
+func NewWebhookService(s *server.Server, webhookRepo repository.WebhookRepository) *WebhookService {
+	return &WebhookService{
+		server:      s,
+		webhookRepo: webhookRepo,
+		client:      &http.Client{Timeout: s.Config.Webhooks.RequestTimeout},
+	}
+}
+
+// SignWebhookPayload computes the v1 signature sent in X-Webhook-Signature:
+// hex(HMAC-SHA256(secret, "<timestamp>.<body>")).
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func (s *WebhookService) CreateEndpoint(ctx context.Context, createdBy *uuid.UUID, payload *webhook.CreateEndpointPayload) (*webhook.CreateEndpointResponse, error) {
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, err := s.webhookRepo.CreateEndpoint(ctx, &webhook.Endpoint{
+		URL:         payload.URL,
+		Description: payload.Description,
+		Secret:      secret,
+		EventTypes:  payload.EventTypes,
+		Enabled:     true,
+		CreatedBy:   createdBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &webhook.CreateEndpointResponse{
+		EndpointResponse: toEndpointResponse(endpoint),
+		Secret:           secret,
+	}, nil
+}
+
+func (s *WebhookService) GetEndpoints(ctx context.Context, limit, offset int) ([]webhook.EndpointResponse, error) {
+	endpoints, err := s.webhookRepo.GetEndpoints(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]webhook.EndpointResponse, 0, len(endpoints))
+	for _, e := range endpoints {
+		responses = append(responses, toEndpointResponse(e))
+	}
+
+	return responses, nil
+}
+
+func (s *WebhookService) GetEndpoint(ctx context.Context, id uuid.UUID) (*webhook.EndpointResponse, error) {
+	endpoint, err := s.getEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := toEndpointResponse(endpoint)
+	return &response, nil
+}
+
+func (s *WebhookService) UpdateEndpoint(ctx context.Context, id uuid.UUID, payload *webhook.UpdateEndpointPayload) (*webhook.EndpointResponse, error) {
+	endpoint, err := s.getEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.URL != nil {
+		endpoint.URL = *payload.URL
+	}
+	if payload.Description != nil {
+		endpoint.Description = payload.Description
+	}
+	if payload.EventTypes != nil {
+		endpoint.EventTypes = payload.EventTypes
+	}
+	if payload.Enabled != nil && *payload.Enabled != endpoint.Enabled {
+		endpoint.Enabled = *payload.Enabled
+		if endpoint.Enabled {
+			// Re-enabling gives the endpoint a clean slate
+			endpoint.ConsecutiveFailures = 0
+			endpoint.DisabledAt = nil
+			endpoint.DisabledReason = nil
+		} else {
+			now := time.Now()
+			reason := webhookDisabledByAdmin
+			endpoint.DisabledAt = &now
+			endpoint.DisabledReason = &reason
+		}
+	}
+
+	if err := s.webhookRepo.UpdateEndpoint(ctx, endpoint); err != nil {
+		return nil, err
+	}
+
+	response := toEndpointResponse(endpoint)
+	return &response, nil
+}
+
+func (s *WebhookService) DeleteEndpoint(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.getEndpoint(ctx, id); err != nil {
+		return err
+	}
+
+	return s.webhookRepo.DeleteEndpoint(ctx, id)
+}
+
+func (s *WebhookService) GetDeliveries(ctx context.Context, endpointID uuid.UUID, limit, offset int) ([]webhook.DeliveryResponse, error) {
+	if _, err := s.getEndpoint(ctx, endpointID); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.webhookRepo.GetDeliveriesByEndpoint(ctx, endpointID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]webhook.DeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		responses = append(responses, toDeliveryResponse(d))
+	}
+
+	return responses, nil
+}
+
+// Redeliver queues a fresh copy of an earlier delivery, keeping the original event id
+// so receivers can de-duplicate.
+func (s *WebhookService) Redeliver(ctx context.Context, endpointID, deliveryID uuid.UUID) (*webhook.DeliveryResponse, error) {
+	endpoint, err := s.getEndpoint(ctx, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	if !endpoint.Enabled {
+		return nil, errs.NewBadRequestError("webhook endpoint is disabled", true, nil, nil, nil)
+	}
+
+	original, err := s.webhookRepo.GetDeliveryByID(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if original == nil || original.EndpointID != endpointID {
+		return nil, errs.NewNotFoundError("webhook delivery not found", true, nil)
+	}
+
+	now := time.Now()
+	delivery, err := s.webhookRepo.CreateDelivery(ctx, &webhook.Delivery{
+		EndpointID:    endpointID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        webhook.DeliveryStatusPending,
+		NextAttemptAt: &now,
+		RedeliveryOf:  &original.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := toDeliveryResponse(delivery)
+	return &response, nil
+}
+
+// Publish enqueues a delivery of the event for every enabled endpoint subscribed to it.
+// It never fails the caller: enqueue errors are logged and the event is dropped.
+func (s *WebhookService) Publish(ctx context.Context, eventType webhook.EventType, data any) {
+	logger := s.server.Logger.With().Str("event_type", string(eventType)).Logger()
+
+	endpoints, err := s.webhookRepo.GetEnabledEndpointsForEvent(ctx, eventType)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to load webhook endpoints")
+		return
+	}
+	if len(endpoints) == 0 {
+		return
+	}
+
+	event := webhook.Event{
+		ID:        uuid.New(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to encode webhook event")
+		return
+	}
+
+	now := time.Now()
+	for _, endpoint := range endpoints {
+		_, err := s.webhookRepo.CreateDelivery(ctx, &webhook.Delivery{
+			EndpointID:    endpoint.ID,
+			EventID:       event.ID,
+			EventType:     eventType,
+			Payload:       payload,
+			Status:        webhook.DeliveryStatusPending,
+			NextAttemptAt: &now,
+		})
+		if err != nil {
+			logger.Error().Err(err).Str("endpoint_id", endpoint.ID.String()).Msg("failed to enqueue webhook delivery")
+		}
+	}
+}
+
+// RunDispatcher delivers due webhooks until ctx is cancelled
+func (s *WebhookService) RunDispatcher(ctx context.Context) {
+	cfg := s.server.Config.Webhooks
+	if !cfg.Enabled {
+		s.server.Logger.Info().Msg("webhook dispatcher disabled")
+		return
+	}
+
+	ticker := time.NewTicker(cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.dispatchDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *WebhookService) dispatchDue(ctx context.Context) {
+	cfg := s.server.Config.Webhooks
+
+	// Lease long enough to cover the request and the bookkeeping after it
+	deliveries, err := s.webhookRepo.ClaimDueDeliveries(ctx, cfg.BatchSize, 2*cfg.RequestTimeout)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.server.Logger.Error().Err(err).Msg("failed to claim webhook deliveries")
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range deliveries {
+		wg.Add(1)
+		go func(d *webhook.Delivery) {
+			defer wg.Done()
+			s.attempt(ctx, d)
+		}(d)
+	}
+	wg.Wait()
+}
+
+func (s *WebhookService) attempt(ctx context.Context, d *webhook.Delivery) {
+	ctx, span := tracing.Start(ctx, "WebhookService.attempt")
+	defer span.End()
+
+	cfg := s.server.Config.Webhooks
+	logger := s.server.Logger.With().
+		Str("delivery_id", d.ID.String()).
+		Str("endpoint_id", d.EndpointID.String()).
+		Str("event_type", string(d.EventType)).
+		Logger()
+
+	// Bookkeeping must survive shutdown so the attempt is not lost
+	storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.RequestTimeout)
+	defer cancel()
+
+	endpoint, err := s.webhookRepo.GetEndpointByID(storeCtx, d.EndpointID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to load webhook endpoint")
+		return
+	}
+
+	start := time.Now()
+	previousAttemptAt := d.LastAttemptAt
+	d.LastAttemptAt = &start
+
+	if endpoint == nil || !endpoint.Enabled {
+		msg := "endpoint disabled"
+		d.Status = webhook.DeliveryStatusFailed
+		d.NextAttemptAt = nil
+		d.Error = &msg
+		if err := s.webhookRepo.UpdateDelivery(storeCtx, d); err != nil {
+			logger.Error().Err(err).Msg("failed to record webhook delivery")
+		}
+		return
+	}
+
+	d.Attempts++
+	status, body, sendErr := s.send(ctx, endpoint, d)
+	if sendErr != nil && ctx.Err() != nil {
+		// Cut short by shutdown, which is not the endpoint's fault: release the
+		// lease without counting the attempt so the delivery is retried promptly
+		d.Attempts--
+		d.LastAttemptAt = previousAttemptAt
+		d.NextAttemptAt = &start
+		if err := s.webhookRepo.UpdateDelivery(storeCtx, d); err != nil {
+			logger.Error().Err(err).Msg("failed to release webhook delivery")
+		}
+		logger.Info().Msg("webhook delivery interrupted by shutdown, left pending")
+		return
+	}
+	duration := int(time.Since(start).Milliseconds())
+	d.DurationMs = &duration
+	d.ResponseStatus = status
+	d.ResponseBody = body
+
+	if sendErr == nil {
+		d.Status = webhook.DeliveryStatusSucceeded
+		d.NextAttemptAt = nil
+		d.Error = nil
+		if err := s.webhookRepo.RecordEndpointSuccess(storeCtx, endpoint.ID); err != nil {
+			logger.Error().Err(err).Msg("failed to reset webhook endpoint failures")
+		}
+	} else {
+		msg := sendErr.Error()
+		d.Error = &msg
+		if d.Attempts >= cfg.MaxAttempts {
+			d.Status = webhook.DeliveryStatusFailed
+			d.NextAttemptAt = nil
+		} else {
+			next := time.Now().Add(s.backoff(d.Attempts))
+			d.Status = webhook.DeliveryStatusPending
+			d.NextAttemptAt = &next
+		}
+
+		disabled, err := s.webhookRepo.RecordEndpointFailure(storeCtx, endpoint.ID, cfg.DisableAfterFailures, webhookDisabledByFailure)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to record webhook endpoint failure")
+		} else if disabled {
+			logger.Warn().
+				Int("threshold", cfg.DisableAfterFailures).
+				Msg("webhook endpoint disabled after repeated failures")
+		}
+
+		logger.Warn().
+			Err(sendErr).
+			Int("attempt", d.Attempts).
+			Str("status", string(d.Status)).
+			Msg("webhook delivery attempt failed")
+	}
+
+	if err := s.webhookRepo.UpdateDelivery(storeCtx, d); err != nil {
+		logger.Error().Err(err).Msg("failed to record webhook delivery")
+	}
+}
+
+// send posts the payload and returns the response status and a truncated body.
+// Any non-2xx response is reported as an error.
+func (s *WebhookService) send(ctx context.Context, endpoint *webhook.Endpoint, d *webhook.Delivery) (*int, *string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "jwt-webhooks/1.0")
+	req.Header.Set(WebhookEventIDHeader, d.EventID.String())
+	req.Header.Set(WebhookEventTypeHeader, string(d.EventType))
+	req.Header.Set(WebhookDeliveryIDHeader, d.ID.String())
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(endpoint.Secret, timestamp, d.Payload))
+	// Let receivers that trace continue the delivery's trace
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, webhookMaxResponseBody))
+	status := resp.StatusCode
+	body := string(raw)
+
+	if status < 200 || status >= 300 {
+		return &status, &body, fmt.Errorf("endpoint responded with status %d", status)
+	}
+
+	return &status, &body, nil
+}
+
+// backoff doubles the delay per attempt up to the configured ceiling, plus up to 20% jitter
+func (s *WebhookService) backoff(attempt int) time.Duration {
+	cfg := s.server.Config.Webhooks
+
+	delay := cfg.InitialBackoff
+	for i := 1; i < attempt && delay < cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > cfg.MaxBackoff {
+		delay = cfg.MaxBackoff
+	}
+
+	return delay + time.Duration(mathrand.Int64N(int64(delay)/5+1)) // #nosec G404 -- jitter only
+}
+
+func (s *WebhookService) getEndpoint(ctx context.Context, id uuid.UUID) (*webhook.Endpoint, error) {
+	endpoint, err := s.webhookRepo.GetEndpointByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint == nil {
+		return nil, errs.NewNotFoundError("webhook endpoint not found", true, nil)
+	}
+	return endpoint, nil
+}
+
+func toEndpointResponse(e *webhook.Endpoint) webhook.EndpointResponse {
+	return webhook.EndpointResponse{
+		ID:                  e.ID,
+		URL:                 e.URL,
+		Description:         e.Description,
+		EventTypes:          e.EventTypes,
+		Enabled:             e.Enabled,
+		ConsecutiveFailures: e.ConsecutiveFailures,
+		DisabledAt:          e.DisabledAt,
+		DisabledReason:      e.DisabledReason,
+		CreatedAt:           e.CreatedAt,
+		UpdatedAt:           e.UpdatedAt,
+	}
+}
+
+func toDeliveryResponse(d *webhook.Delivery) webhook.DeliveryResponse {
+	return webhook.DeliveryResponse{
+		ID:             d.ID,
+		EndpointID:     d.EndpointID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastAttemptAt:  d.LastAttemptAt,
+		ResponseStatus: d.ResponseStatus,
+		ResponseBody:   d.ResponseBody,
+		Error:          d.Error,
+		DurationMs:     d.DurationMs,
+		RedeliveryOf:   d.RedeliveryOf,
+		CreatedAt:      d.CreatedAt,
+	}
+}
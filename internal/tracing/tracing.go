@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/2SSK/jwt/internal/config"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/2SSK/jwt"
+
+// Setup installs the W3C trace context propagator and, when tracing is enabled,
+// a tracer provider exporting to the configured exporter. The propagator is
+// installed either way so incoming trace ids still reach the logs. The returned
+// function flushes and stops the exporter.
+func Setup(ctx context.Context, cfg *config.ObservabilityConfig, logger *zerolog.Logger) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if !cfg.Tracing.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Tracing.Exporter {
+	case config.TracingExporterStdout:
+		exporter, err = stdouttrace.New()
+	default:
+		opts := []otlptracehttp.Option{}
+		if cfg.Tracing.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Tracing.Endpoint))
+		}
+		if cfg.Tracing.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", cfg.Tracing.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", cfg.ServiceName),
+		attribute.String("deployment.environment.name", cfg.Environment),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn().Err(err).Msg("tracing error")
+	}))
+
+	logger.Info().Str("exporter", cfg.Tracing.Exporter).Float64("sample_ratio", cfg.Tracing.SampleRatio).Msg("tracing enabled")
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span, a child of the span in ctx if there is one
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
@@ -7,8 +7,10 @@ import (
 	"strings"
 
 	"github.com/2SSK/jwt/internal/errs"
+	"github.com/2SSK/jwt/internal/tracing"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/codes"
 )
 
 type Validatable interface {
@@ -26,7 +28,22 @@ func (c CustomValidationErrors) Error() string {
 	return "Validation failed"
 }
 
+// BindAndValidate binds the request to payload and validates it, in a span of its
+// own so traces show the validation phase of the request
 func BindAndValidate(c echo.Context, payload Validatable) error {
+	_, span := tracing.Start(c.Request().Context(), "validation")
+	defer span.End()
+
+	err := bindAndValidate(c, payload)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return err
+}
+
+func bindAndValidate(c echo.Context, payload Validatable) error {
 	if err := c.Bind(payload); err != nil {
 		message := strings.Split(strings.Split(err.Error(), ",")[1], "message=")[1]
 		return errs.NewBadRequestError(message, false, nil, nil, nil)
@@ -78,6 +95,14 @@ func extractValidationErrors(err error) (string, []errs.FieldError) {
 			} else {
 				msg = fmt.Sprintf("must not exceed %s", err.Param())
 			}
+		case "required_without":
+			msg = fmt.Sprintf("is required when %s is not provided", strings.ToLower(err.Param()))
+		case "required_with":
+			msg = fmt.Sprintf("is required when %s is provided", strings.ToLower(err.Param()))
+		case "len":
+			msg = fmt.Sprintf("must be exactly %s characters", err.Param())
+		case "numeric":
+			msg = "must contain only digits"
 		case "oneof":
 			msg = fmt.Sprintf("must be one of: %s", err.Param())
 		case "email":
@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+type SignUpRequest struct {
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Phone     string `json:"phone,omitempty"`
+	UserType  string `json:"userType,omitempty"`
+}
+
+type SignUpResponse struct {
+	User UserResponse `json:"user"`
+	TokenResponse
+}
+
+type LoginResponse struct {
+	User UserResponse `json:"user"`
+	TokenResponse
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"accessToken"`
+	TokenType    string `json:"tokenType"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	// CSRFToken replaces RefreshToken for clients whose refresh token is set as a
+	// cookie, which this client does not use
+	CSRFToken string `json:"csrfToken,omitempty"`
+}
+
+// MFAChallengeResponse is returned by login instead of tokens when the user has a
+// second factor enrolled
+type MFAChallengeResponse struct {
+	MFARequired bool     `json:"mfaRequired"`
+	MFAToken    string   `json:"mfaToken"`
+	Methods     []string `json:"methods"`
+	ExpiresIn   int      `json:"expiresIn"`
+}
+
+// ReauthenticateRequest proves the user's identity again with their password, a
+// second factor, or both
+type ReauthenticateRequest struct {
+	Password     string `json:"password,omitempty"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recoveryCode,omitempty"`
+}
+
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type verifyMFARequest struct {
+	MFAToken     string `json:"mfaToken"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recoveryCode,omitempty"`
+}
+
+// MFARequiredError is returned by Login for users with a second factor. The login
+// is completed by VerifyMFA or VerifyMFARecoveryCode with the challenge's MFAToken.
+type MFARequiredError struct {
+	Challenge *MFAChallengeResponse
+}
+
+func (e *MFARequiredError) Error() string {
+	return "client: multi-factor authentication required"
+}
+
+// SignUp creates an account and signs the client in as the new user
+func (c *Client) SignUp(ctx context.Context, req *SignUpRequest) (*SignUpResponse, error) {
+	var resp SignUpResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/auth/signup", authNone, req, &resp); err != nil {
+		return nil, err
+	}
+
+	c.setTokens(Tokens{AccessToken: resp.AccessToken, RefreshToken: resp.RefreshToken})
+	return &resp, nil
+}
+
+// Login signs the client in with a password
+func (c *Client) Login(ctx context.Context, email, password string) (*LoginResponse, error) {
+	var resp struct {
+		LoginResponse
+		MFAChallengeResponse
+	}
+	payload := loginRequest{Email: email, Password: password}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/auth/login", authNone, &payload, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.MFARequired {
+		return nil, &MFARequiredError{Challenge: &resp.MFAChallengeResponse}
+	}
+
+	c.setTokens(Tokens{AccessToken: resp.AccessToken, RefreshToken: resp.RefreshToken})
+	return &resp.LoginResponse, nil
+}
+
+// VerifyMFA completes a login that returned MFARequiredError with a TOTP code
+func (c *Client) VerifyMFA(ctx context.Context, mfaToken, code string) (*LoginResponse, error) {
+	return c.verifyMFA(ctx, &verifyMFARequest{MFAToken: mfaToken, Code: code})
+}
+
+// VerifyMFARecoveryCode completes a login that returned MFARequiredError with one
+// of the user's recovery codes, which is used up
+func (c *Client) VerifyMFARecoveryCode(ctx context.Context, mfaToken, recoveryCode string) (*LoginResponse, error) {
+	return c.verifyMFA(ctx, &verifyMFARequest{MFAToken: mfaToken, RecoveryCode: recoveryCode})
+}
+
+func (c *Client) verifyMFA(ctx context.Context, req *verifyMFARequest) (*LoginResponse, error) {
+	var resp LoginResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/auth/mfa/verify", authNone, req, &resp); err != nil {
+		return nil, err
+	}
+
+	c.setTokens(Tokens{AccessToken: resp.AccessToken, RefreshToken: resp.RefreshToken})
+	return &resp, nil
+}
+
+// Refresh exchanges the refresh token for new tokens. Calls refresh automatically
+// when the access token is rejected, so this is rarely needed.
+func (c *Client) Refresh(ctx context.Context) (*TokenResponse, error) {
+	var resp TokenResponse
+	if err := c.send(ctx, http.MethodPost, "/api/v1/auth/refresh", authRefresh, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	c.setTokens(Tokens{AccessToken: resp.AccessToken, RefreshToken: resp.RefreshToken})
+	return &resp, nil
+}
+
+// Reauthenticate proves the user's identity again before routes that require a
+// recent login, such as updating or deleting users, and stores the new tokens
+func (c *Client) Reauthenticate(ctx context.Context, req *ReauthenticateRequest) (*TokenResponse, error) {
+	var resp TokenResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/auth/reauthenticate", authAccess, req, &resp); err != nil {
+		return nil, err
+	}
+
+	c.setTokens(Tokens{AccessToken: resp.AccessToken, RefreshToken: resp.RefreshToken})
+	return &resp, nil
+}
+
+// Logout revokes the client's session and forgets its tokens
+func (c *Client) Logout(ctx context.Context) error {
+	if err := c.do(ctx, http.MethodPost, "/api/v1/auth/logout", authAccess, nil, nil); err != nil {
+		return err
+	}
+
+	c.setTokens(Tokens{})
+	return nil
+}
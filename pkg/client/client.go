@@ -0,0 +1,314 @@
+// Package client is a Go client for the auth API. It signs users up and in, keeps
+// their tokens refreshed and calls the admin user routes:
+//
+//	c := client.New("https://auth.example.com")
+//	if _, err := c.Login(ctx, "admin@example.com", password); err != nil {
+//		return err
+//	}
+//	users, err := c.ListUsers(ctx, 10, 0)
+//
+// A request rejected with 401 is retried once after refreshing the access token.
+// Error responses are returned as *HTTPError. Requests that fail with 429 or 503,
+// and GET, PUT and DELETE requests that fail with a network error, 502 or 504, are
+// retried with exponential backoff until the retries are used up or the context
+// is done. A Retry-After longer than a minute, or than the context's deadline
+// allows, is not waited for: the error is returned instead. The client only
+// handles bearer tokens, not DPoP.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPError is the error body of every API response. Calls return it for
+// responses with a 4xx or 5xx status.
+type HTTPError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Status  int    `json:"status"`
+	// Errors lists the fields a request was rejected for
+	Errors []FieldError `json:"errors"`
+	// Action is what the caller should do next, such as following a redirect
+	Action *Action `json:"action"`
+	// RetryAfter is the number of seconds to wait before retrying, if any
+	RetryAfter int `json:"retryAfter,omitempty"`
+}
+
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+type FieldError struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+type Action struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Value   string `json:"value"`
+}
+
+// Tokens are the access and refresh tokens the client authenticates with
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	clientID   string
+	retries    int
+	backoff    time.Duration
+	onRefresh  func(Tokens)
+
+	mu     sync.Mutex
+	tokens Tokens
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client requests are sent with
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithClientID sends X-Client-Id when signing in, which selects the client's
+// token lifetimes and format
+func WithClientID(clientID string) Option {
+	return func(c *Client) { c.clientID = clientID }
+}
+
+// WithRetries sets how often a failed request is retried and the delay before the
+// first retry, which doubles after each one. The default is 2 retries after 200ms.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) { c.retries, c.backoff = retries, backoff }
+}
+
+// WithTokens starts the client with tokens from an earlier session
+func WithTokens(tokens Tokens) Option {
+	return func(c *Client) { c.tokens = tokens }
+}
+
+// OnTokens is called whenever the client gets new tokens, to persist them
+func OnTokens(fn func(Tokens)) Option {
+	return func(c *Client) { c.onRefresh = fn }
+}
+
+// New creates a client for the API at baseURL, e.g. "https://auth.example.com"
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		retries:    2,
+		backoff:    200 * time.Millisecond,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Tokens returns the current tokens
+func (c *Client) Tokens() Tokens {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokens
+}
+
+func (c *Client) setTokens(tokens Tokens) {
+	c.mu.Lock()
+	c.tokens = tokens
+	c.mu.Unlock()
+
+	if c.onRefresh != nil {
+		c.onRefresh(tokens)
+	}
+}
+
+// maxRetryAfter is the longest Retry-After the client waits for before retrying
+const maxRetryAfter = time.Minute
+
+// auth selects the token a request is sent with
+type auth int
+
+const (
+	authNone auth = iota
+	authAccess
+	authRefresh
+)
+
+// do sends a request and decodes a JSON response into out, which may be nil. An
+// access token rejected with 401 is refreshed and the request sent once more.
+func (c *Client) do(ctx context.Context, method, path string, a auth, in, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	accessToken := c.Tokens().AccessToken
+	err := c.send(ctx, method, path, a, body, out)
+
+	var httpErr *HTTPError
+	if a != authAccess || !errors.As(err, &httpErr) || httpErr.Status != http.StatusUnauthorized {
+		return err
+	}
+	if refreshErr := c.refreshAfter(ctx, accessToken); refreshErr != nil {
+		return err
+	}
+
+	return c.send(ctx, method, path, a, body, out)
+}
+
+// refreshAfter refreshes the tokens unless another request already replaced the
+// rejected access token
+func (c *Client) refreshAfter(ctx context.Context, rejected string) error {
+	tokens := c.Tokens()
+	if tokens.AccessToken != rejected {
+		return nil
+	}
+	if tokens.RefreshToken == "" {
+		return errors.New("client: no refresh token")
+	}
+
+	_, err := c.Refresh(ctx)
+	return err
+}
+
+// send makes a request, retrying transient failures
+func (c *Client) send(ctx context.Context, method, path string, a auth, body []byte, out any) error {
+	delay := c.backoff
+
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.sendOnce(ctx, method, path, a, body, out)
+		if err == nil || attempt >= c.retries || !retryable(method, err) || retryAfter > maxRetryAfter {
+			return err
+		}
+
+		wait := delay + rand.N(delay/2+1)
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		delay *= 2
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) sendOnce(ctx context.Context, method, path string, a auth, body []byte, out any) (time.Duration, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.clientID != "" {
+		req.Header.Set("X-Client-Id", c.clientID)
+	}
+
+	tokens := c.Tokens()
+	switch a {
+	case authAccess:
+		req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	case authRefresh:
+		req.Header.Set("Authorization", "Bearer "+tokens.RefreshToken)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return retryAfterOf(resp), decodeError(resp)
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return 0, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return 0, fmt.Errorf("client: failed to decode response: %w", err)
+	}
+
+	return 0, nil
+}
+
+// decodeError reads an error body, falling back to the status for bodies that are
+// not one, such as those of a proxy in front of the API
+func decodeError(resp *http.Response) *HTTPError {
+	httpErr := &HTTPError{}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err := json.Unmarshal(data, httpErr); err != nil || httpErr.Message == "" {
+		httpErr = &HTTPError{
+			Code:    strings.ToUpper(strings.ReplaceAll(http.StatusText(resp.StatusCode), " ", "_")),
+			Message: http.StatusText(resp.StatusCode),
+		}
+	}
+	httpErr.Status = resp.StatusCode
+	return httpErr
+}
+
+func retryAfterOf(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// retryable reports whether a failed request may be sent again. 429 and 503 mean
+// the request was not processed; other failures only for idempotent methods.
+func retryable(method string, err error) bool {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		// Network errors, unless the context ended
+		var urlErr *url.Error
+		return errors.As(err, &urlErr) && idempotent(method) &&
+			!errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	switch httpErr.Status {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent(method)
+	}
+	return false
+}
+
+func idempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete
+}
@@ -0,0 +1,140 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The admin user routes require the signed in user to be an admin. Updating and
+// deleting users and resetting passwords also require a recent login: they fail
+// with a 403 REAUTHENTICATION_REQUIRED HTTPError until Reauthenticate is called.
+
+type UserResponse struct {
+	ID        uuid.UUID `json:"id"`
+	FirstName *string   `json:"firstName"`
+	LastName  *string   `json:"lastName"`
+	Email     *string   `json:"email"`
+	Phone     *string   `json:"phone"`
+	UserType  *string   `json:"userType"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// UpdateUserRequest changes the fields that are set and leaves the others alone
+type UpdateUserRequest struct {
+	FirstName *string `json:"firstName,omitempty"`
+	LastName  *string `json:"lastName,omitempty"`
+	Email     *string `json:"email,omitempty"`
+	Phone     *string `json:"phone,omitempty"`
+	UserType  *string `json:"userType,omitempty"`
+}
+
+type SessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	ClientID   string    `json:"clientId"`
+	DeviceName string    `json:"deviceName"`
+	IPAddress  string    `json:"ipAddress"`
+	UserAgent  string    `json:"userAgent"`
+	// Current marks the session the request was made with
+	Current    bool      `json:"current"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type RevokeSessionsResponse struct {
+	Revoked int `json:"revoked"`
+}
+
+type LoginEventResponse struct {
+	ID                uuid.UUID `json:"id"`
+	Method            string    `json:"method"`
+	Success           bool      `json:"success"`
+	FailureReason     *string   `json:"failureReason"`
+	IPAddress         string    `json:"ipAddress"`
+	UserAgent         string    `json:"userAgent"`
+	DeviceFingerprint string    `json:"deviceFingerprint"`
+	CreatedAt         time.Time `json:"createdAt"`
+}
+
+type resetPasswordRequest struct {
+	NewPassword string `json:"newPassword"`
+}
+
+func (c *Client) ListUsers(ctx context.Context, limit, offset int) ([]*UserResponse, error) {
+	var resp []*UserResponse
+	path := fmt.Sprintf("/api/v1/users?limit=%d&offset=%d", limit, offset)
+	if err := c.do(ctx, http.MethodGet, path, authAccess, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) GetUser(ctx context.Context, userID uuid.UUID) (*UserResponse, error) {
+	var resp UserResponse
+	if err := c.do(ctx, http.MethodGet, userPath(userID, ""), authAccess, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) UpdateUser(ctx context.Context, userID uuid.UUID, req *UpdateUserRequest) (*UserResponse, error) {
+	var resp UserResponse
+	if err := c.do(ctx, http.MethodPut, userPath(userID, ""), authAccess, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	return c.do(ctx, http.MethodDelete, userPath(userID, ""), authAccess, nil, nil)
+}
+
+// ResetPassword sets a user's password and signs them out everywhere
+func (c *Client) ResetPassword(ctx context.Context, userID uuid.UUID, newPassword string) error {
+	payload := resetPasswordRequest{NewPassword: newPassword}
+	return c.do(ctx, http.MethodPut, userPath(userID, "/password"), authAccess, &payload, nil)
+}
+
+// UnlockUser lifts a lockout after failed logins
+func (c *Client) UnlockUser(ctx context.Context, userID uuid.UUID) error {
+	return c.do(ctx, http.MethodPost, userPath(userID, "/unlock"), authAccess, nil, nil)
+}
+
+func (c *Client) GetUserLogins(ctx context.Context, userID uuid.UUID, limit, offset int) ([]LoginEventResponse, error) {
+	var resp []LoginEventResponse
+	path := userPath(userID, fmt.Sprintf("/logins?limit=%d&offset=%d", limit, offset))
+	if err := c.do(ctx, http.MethodGet, path, authAccess, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) GetUserSessions(ctx context.Context, userID uuid.UUID) ([]SessionResponse, error) {
+	var resp []SessionResponse
+	if err := c.do(ctx, http.MethodGet, userPath(userID, "/sessions"), authAccess, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RevokeUserSessions signs a user out everywhere
+func (c *Client) RevokeUserSessions(ctx context.Context, userID uuid.UUID) (*RevokeSessionsResponse, error) {
+	var resp RevokeSessionsResponse
+	if err := c.do(ctx, http.MethodDelete, userPath(userID, "/sessions"), authAccess, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) RevokeUserSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	return c.do(ctx, http.MethodDelete, userPath(userID, "/sessions/"+sessionID.String()), authAccess, nil, nil)
+}
+
+func userPath(userID uuid.UUID, suffix string) string {
+	return "/api/v1/user/" + userID.String() + suffix
+}
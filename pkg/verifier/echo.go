@@ -0,0 +1,36 @@
+package verifier
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ClaimsKey is the echo.Context key the Echo middleware stores the claims under
+const ClaimsKey = "verifier_claims"
+
+// EchoMiddleware is Middleware for Echo. The claims are stored in the context
+// under ClaimsKey and in the request context, see ClaimsFromEcho.
+func (v *Verifier) EchoMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, err := v.VerifyRequest(c.Request())
+			if err != nil {
+				c.Response().Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
+			}
+
+			c.Set(ClaimsKey, claims)
+			c.SetRequest(c.Request().WithContext(context.WithValue(c.Request().Context(), contextKey{}, claims)))
+
+			return next(c)
+		}
+	}
+}
+
+// ClaimsFromEcho returns the claims the Echo middleware stored
+func ClaimsFromEcho(c echo.Context) (*Claims, bool) {
+	claims, ok := c.Get(ClaimsKey).(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,143 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// keySet caches the keys fetched from the JWKS endpoint. Fetches run outside the
+// lock, one at a time, so verifying with known keys never waits on the endpoint.
+type keySet struct {
+	options Options
+
+	mu        sync.Mutex
+	keys      map[string]any
+	fetchedAt time.Time
+	// attemptedAt is when the last fetch finished, successful or not
+	attemptedAt time.Time
+	// fetching is closed when the fetch in progress finishes, nil when idle
+	fetching chan struct{}
+	fetchErr error
+}
+
+func newKeySet(options Options) *keySet {
+	return &keySet{options: options}
+}
+
+// key returns the public key for a kid. Known keys are returned at once, and
+// refreshed in the background once the cache is stale. Unknown kids, as seen
+// right after the auth service adds a key, wait for a fetch. Fetches, failed ones
+// included, happen at most every MinRefreshInterval.
+func (k *keySet) key(ctx context.Context, kid string) (any, error) {
+	k.mu.Lock()
+
+	now := time.Now()
+	key, ok := k.keys[kid]
+	stale := now.Sub(k.fetchedAt) >= k.options.CacheTTL
+	canFetch := now.Sub(k.attemptedAt) >= k.options.MinRefreshInterval
+
+	if ok {
+		// Keep verifying with the keys already known while the endpoint is down
+		if stale && canFetch {
+			k.startFetch()
+		}
+		k.mu.Unlock()
+		return key, nil
+	}
+
+	if k.fetching == nil && !canFetch {
+		err := k.fetchErr
+		k.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrUnknownKey
+	}
+
+	if k.fetching == nil {
+		k.startFetch()
+	}
+	done := k.fetching
+	k.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if key, ok = k.keys[kid]; ok {
+		return key, nil
+	}
+	if k.fetchErr != nil {
+		return nil, k.fetchErr
+	}
+	return nil, ErrUnknownKey
+}
+
+// startFetch fetches the keys in the background. The caller holds the lock.
+func (k *keySet) startFetch() {
+	if k.fetching != nil {
+		return
+	}
+
+	done := make(chan struct{})
+	k.fetching = done
+
+	go func() {
+		keys, err := k.fetch(context.Background())
+
+		k.mu.Lock()
+		if err == nil {
+			k.keys = keys
+			k.fetchedAt = time.Now()
+		}
+		k.fetchErr = err
+		k.attemptedAt = time.Now()
+		k.fetching = nil
+		k.mu.Unlock()
+
+		close(done)
+	}()
+}
+
+func (k *keySet) fetch(ctx context.Context) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.options.JWKSURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := k.options.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("verifier: failed to fetch keys: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("verifier: failed to fetch keys: %s", resp.Status)
+	}
+
+	var set jose.JSONWebKeySet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("verifier: failed to decode keys: %w", err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		keys[key.KeyID] = key.Key
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,68 @@
+package verifier
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+type contextKey struct{}
+
+// ClaimsFromContext returns the claims the middleware stored for a request
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*Claims)
+	return claims, ok
+}
+
+// Middleware rejects requests without a valid bearer token with 401 and passes the
+// token's claims on in the request context, see ClaimsFromContext
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, err := v.VerifyRequest(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, claims)))
+	})
+}
+
+// ErrSenderConstrained is returned for tokens bound to a DPoP key, which need a
+// proof this package does not check, or to a client certificate the request was
+// not made with
+var ErrSenderConstrained = errors.New("token is bound to a key or certificate the request does not prove")
+
+// VerifyRequest verifies the bearer token in a request's Authorization header.
+// Certificate-bound tokens (RFC 8705) are only accepted over a TLS connection
+// presenting that certificate, and DPoP-bound tokens are rejected.
+func (v *Verifier) VerifyRequest(r *http.Request) (*Claims, error) {
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if scheme != "Bearer" || token == "" {
+		return nil, ErrInvalidToken
+	}
+
+	claims, err := v.Verify(r.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := claims.Confirmation["jkt"]; ok {
+		return nil, ErrSenderConstrained
+	}
+	if thumbprint, ok := claims.Confirmation["x5t#S256"]; ok {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			return nil, ErrSenderConstrained
+		}
+		sum := sha256.Sum256(r.TLS.PeerCertificates[0].Raw)
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != thumbprint {
+			return nil, ErrSenderConstrained
+		}
+	}
+
+	return claims, nil
+}
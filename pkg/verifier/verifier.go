@@ -0,0 +1,158 @@
+// Package verifier checks access tokens issued by the auth service in other
+// services. Tokens must be JWTs signed with one of the service's signing keys
+// (tokens.signing_keys_dir), which are fetched from its JWKS endpoint and cached.
+//
+// A verifier only checks the token itself: a token stays valid until it expires
+// even when its session is revoked. Services that need revocation to take effect
+// at once, or that are issued opaque, PASETO or encrypted tokens, use the
+// introspection endpoint instead.
+//
+//	v, err := verifier.New(verifier.Options{
+//		JWKSURL:  "https://auth.example.com/.well-known/jwks.json",
+//		Issuer:   "https://auth.example.com",
+//		Audience: "billing",
+//	})
+//	mux.Handle("/invoices", v.Middleware(invoices))
+package verifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Token types, carried in the typ claim
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+var (
+	// ErrInvalidToken is returned for malformed tokens, bad signatures and failed
+	// claim checks; errors wrap it with the reason
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrUnknownKey is returned for tokens signed with a key missing from the JWKS
+	ErrUnknownKey = errors.New("unknown signing key")
+)
+
+// DefaultAlgorithms are the asymmetric algorithms the auth service signs with
+var DefaultAlgorithms = []string{"RS256", "ES256", "ES384", "ES512", "EdDSA"}
+
+type Options struct {
+	// JWKSURL is the auth service's /.well-known/jwks.json
+	JWKSURL string
+	// Issuer, when set, must match the iss claim (tokens.issuer)
+	Issuer string
+	// Audience, when set, must be one of the aud claim's values (tokens.audience)
+	Audience string
+	// TokenType is the required typ claim, TokenTypeAccess by default
+	TokenType string
+	// Algorithms limits the accepted signing algorithms, DefaultAlgorithms by default
+	Algorithms []string
+	// Leeway is the clock skew allowed when checking exp and nbf
+	Leeway time.Duration
+	// CacheTTL is how long fetched keys are used before they are fetched again,
+	// 5 minutes by default
+	CacheTTL time.Duration
+	// MinRefreshInterval is the least time between fetches, after a failed one or
+	// for tokens with an unknown kid, 30 seconds by default
+	MinRefreshInterval time.Duration
+	// HTTPClient fetches the keys, with a 10 second timeout by default
+	HTTPClient *http.Client
+}
+
+// Claims are the claims of a verified token
+type Claims struct {
+	UserID    string `json:"user_id"`
+	SessionID string `json:"sid"`
+	Type      string `json:"typ"`
+	// AuthTime is when the user last authenticated, in Unix seconds
+	AuthTime int64    `json:"auth_time,omitempty"`
+	AMR      []string `json:"amr,omitempty"`
+	// ACR is the authentication level, "1" for one factor and "2" for multi-factor
+	ACR string `json:"acr,omitempty"`
+	// Confirmation binds the token to a DPoP key (jkt) or a client certificate
+	// (x5t#S256), see Verifier.Middleware
+	Confirmation map[string]string `json:"cnf,omitempty"`
+	jwt.RegisteredClaims
+}
+
+type Verifier struct {
+	options Options
+	keys    *keySet
+	parser  *jwt.Parser
+}
+
+func New(options Options) (*Verifier, error) {
+	if options.JWKSURL == "" {
+		return nil, errors.New("verifier: JWKSURL is required")
+	}
+	if options.TokenType == "" {
+		options.TokenType = TokenTypeAccess
+	}
+	if len(options.Algorithms) == 0 {
+		options.Algorithms = DefaultAlgorithms
+	}
+	if options.CacheTTL == 0 {
+		options.CacheTTL = 5 * time.Minute
+	}
+	if options.MinRefreshInterval == 0 {
+		options.MinRefreshInterval = 30 * time.Second
+	}
+	if options.HTTPClient == nil {
+		options.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	// A shared secret must never verify these tokens, whatever alg they claim
+	if slices.ContainsFunc(options.Algorithms, func(alg string) bool { return alg == "none" || strings.HasPrefix(alg, "HS") }) {
+		return nil, errors.New("verifier: only asymmetric algorithms are supported")
+	}
+
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods(options.Algorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(options.Leeway),
+	}
+	if options.Issuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(options.Issuer))
+	}
+	if options.Audience != "" {
+		parserOptions = append(parserOptions, jwt.WithAudience(options.Audience))
+	}
+
+	return &Verifier{
+		options: options,
+		keys:    newKeySet(options),
+		parser:  jwt.NewParser(parserOptions...),
+	}, nil
+}
+
+// Verify checks a token's signature, expiry, issuer, audience and type, and
+// returns its claims
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.key(ctx, kid)
+	})
+	if err != nil {
+		if errors.Is(err, ErrUnknownKey) {
+			return nil, ErrUnknownKey
+		}
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	if claims.Type != v.options.TokenType {
+		return nil, fmt.Errorf("%w: token type is %q", ErrInvalidToken, claims.Type)
+	}
+	if claims.UserID == "" || claims.SessionID == "" {
+		return nil, fmt.Errorf("%w: token has no user or session", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,350 @@
+package verifier
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://auth.example.com"
+	testAudience = "billing"
+)
+
+// jwksServer serves the public halves of its keys and counts the fetches
+type jwksServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	keys    map[string]*ecdsa.PrivateKey
+	failing bool
+	delay   time.Duration
+	fetches atomic.Int32
+}
+
+func newJWKSServer(t *testing.T, kids ...string) *jwksServer {
+	t.Helper()
+
+	s := &jwksServer{keys: map[string]*ecdsa.PrivateKey{}}
+	for _, kid := range kids {
+		s.addKey(t, kid)
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		time.Sleep(s.delay)
+
+		if s.failing {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		set := jose.JSONWebKeySet{}
+		for kid, key := range s.keys {
+			set.Keys = append(set.Keys, jose.JSONWebKey{Key: key.Public(), KeyID: kid, Algorithm: "ES256", Use: "sig"})
+		}
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *jwksServer) addKey(t *testing.T, kid string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.Lock()
+	s.keys[kid] = key
+	s.mu.Unlock()
+}
+
+func (s *jwksServer) setFailing(failing bool) {
+	s.mu.Lock()
+	s.failing = failing
+	s.mu.Unlock()
+}
+
+// sign issues a token like the auth service does, with the server's key for kid
+func (s *jwksServer) sign(t *testing.T, kid string, edit func(*Claims)) string {
+	t.Helper()
+
+	s.mu.Lock()
+	key := s.keys[kid]
+	s.mu.Unlock()
+	if key == nil {
+		// A key the server does not publish
+		var err error
+		if key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, validClaims(edit))
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func validClaims(edit func(*Claims)) *Claims {
+	now := time.Now()
+	claims := &Claims{
+		UserID:    "5f0c7d2e-8a3b-4c1d-9e6f-2a4b6c8d0e1f",
+		SessionID: "9b1d3f5a-7c9e-4b2d-8f0a-1c3e5a7b9d2f",
+		Type:      TokenTypeAccess,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testIssuer,
+			Audience:  jwt.ClaimStrings{testAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(15 * time.Minute)),
+		},
+	}
+	if edit != nil {
+		edit(claims)
+	}
+	return claims
+}
+
+func newTestVerifier(t *testing.T, server *jwksServer, edit func(*Options)) *Verifier {
+	t.Helper()
+
+	options := Options{
+		JWKSURL:  server.URL,
+		Issuer:   testIssuer,
+		Audience: testAudience,
+	}
+	if edit != nil {
+		edit(&options)
+	}
+
+	v, err := New(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestVerify(t *testing.T) {
+	server := newJWKSServer(t, "key-1")
+	v := newTestVerifier(t, server, nil)
+
+	claims, err := v.Verify(context.Background(), server.sign(t, "key-1", nil))
+	if err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	if claims.UserID != validClaims(nil).UserID || claims.Type != TokenTypeAccess {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	if _, err := v.Verify(context.Background(), server.sign(t, "key-1", nil)); err != nil {
+		t.Fatalf("second token rejected: %v", err)
+	}
+	if fetches := server.fetches.Load(); fetches != 1 {
+		t.Errorf("fetches = %d, want 1", fetches)
+	}
+}
+
+func TestVerifyClaims(t *testing.T) {
+	server := newJWKSServer(t, "key-1")
+	v := newTestVerifier(t, server, nil)
+
+	cases := []struct {
+		name string
+		edit func(*Claims)
+	}{
+		{"issuer", func(c *Claims) { c.Issuer = "https://evil.example.com" }},
+		{"no issuer", func(c *Claims) { c.Issuer = "" }},
+		{"audience", func(c *Claims) { c.Audience = jwt.ClaimStrings{"payroll"} }},
+		{"no audience", func(c *Claims) { c.Audience = nil }},
+		{"expired", func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) }},
+		{"no expiry", func(c *Claims) { c.ExpiresAt = nil }},
+		{"not yet valid", func(c *Claims) { c.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Minute)) }},
+		{"refresh token", func(c *Claims) { c.Type = TokenTypeRefresh }},
+		{"no type", func(c *Claims) { c.Type = "" }},
+		{"no user", func(c *Claims) { c.UserID = "" }},
+		{"no session", func(c *Claims) { c.SessionID = "" }},
+	}
+
+	for _, c := range cases {
+		_, err := v.Verify(context.Background(), server.sign(t, "key-1", c.edit))
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: err = %v, want ErrInvalidToken", c.name, err)
+		}
+	}
+}
+
+func TestVerifyLeeway(t *testing.T) {
+	server := newJWKSServer(t, "key-1")
+	v := newTestVerifier(t, server, func(o *Options) { o.Leeway = time.Minute })
+
+	token := server.sign(t, "key-1", func(c *Claims) {
+		c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-30 * time.Second))
+	})
+	if _, err := v.Verify(context.Background(), token); err != nil {
+		t.Errorf("token expired within the leeway rejected: %v", err)
+	}
+}
+
+func TestVerifyRejectsSymmetricAndNone(t *testing.T) {
+	server := newJWKSServer(t, "key-1")
+	v := newTestVerifier(t, server, nil)
+
+	hs256 := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims(nil))
+	hs256.Header["kid"] = "key-1"
+	hs256Token, err := hs256.SignedString([]byte("shared secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	none := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims(nil))
+	none.Header["kid"] = "key-1"
+	noneToken, err := none.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The ES256 signature moved under an ES384 header must not verify either
+	es256 := server.sign(t, "key-1", nil)
+	es384 := jwt.NewWithClaims(jwt.SigningMethodES384, validClaims(nil))
+	es384.Header["kid"] = "key-1"
+	es384Signing, err := es384.SigningString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(es256, ".")
+	swapped := strings.Split(es384Signing, ".")[0] + "." + parts[1] + "." + parts[2]
+
+	for name, token := range map[string]string{"HS256": hs256Token, "none": noneToken, "swapped alg": swapped} {
+		if _, err := v.Verify(context.Background(), token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: err = %v, want ErrInvalidToken", name, err)
+		}
+	}
+
+	for _, alg := range []string{"HS256", "none"} {
+		if _, err := New(Options{JWKSURL: server.URL, Algorithms: []string{"ES256", alg}}); err == nil {
+			t.Errorf("New accepted algorithm %s", alg)
+		}
+	}
+}
+
+func TestVerifyUnknownKeyRefetches(t *testing.T) {
+	server := newJWKSServer(t, "key-1")
+	v := newTestVerifier(t, server, func(o *Options) { o.MinRefreshInterval = 50 * time.Millisecond })
+
+	if _, err := v.Verify(context.Background(), server.sign(t, "key-1", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The auth service rotates; concurrent requests with the new kid share one fetch
+	server.addKey(t, "key-2")
+	server.mu.Lock()
+	server.delay = 20 * time.Millisecond
+	server.mu.Unlock()
+	time.Sleep(60 * time.Millisecond)
+
+	token := server.sign(t, "key-2", nil)
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := v.Verify(context.Background(), token)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("token signed with the new key rejected: %v", err)
+		}
+	}
+	if fetches := server.fetches.Load(); fetches != 2 {
+		t.Errorf("fetches = %d, want 2", fetches)
+	}
+
+	// A kid the server does not publish cannot force a fetch per token
+	for range 5 {
+		if _, err := v.Verify(context.Background(), server.sign(t, "key-3", nil)); !errors.Is(err, ErrUnknownKey) {
+			t.Fatalf("err = %v, want ErrUnknownKey", err)
+		}
+	}
+	if fetches := server.fetches.Load(); fetches != 2 {
+		t.Errorf("fetches after unknown kids = %d, want 2", fetches)
+	}
+}
+
+func TestVerifyFetchBackoff(t *testing.T) {
+	server := newJWKSServer(t, "key-1")
+	server.setFailing(true)
+	v := newTestVerifier(t, server, func(o *Options) { o.MinRefreshInterval = 50 * time.Millisecond })
+	token := server.sign(t, "key-1", nil)
+
+	for range 5 {
+		_, err := v.Verify(context.Background(), token)
+		if err == nil || errors.Is(err, ErrUnknownKey) {
+			t.Fatalf("err = %v, want the fetch error", err)
+		}
+	}
+	if fetches := server.fetches.Load(); fetches != 1 {
+		t.Errorf("fetches while failing = %d, want 1", fetches)
+	}
+
+	server.setFailing(false)
+	time.Sleep(60 * time.Millisecond)
+
+	if _, err := v.Verify(context.Background(), token); err != nil {
+		t.Fatalf("token rejected after the endpoint recovered: %v", err)
+	}
+	if fetches := server.fetches.Load(); fetches != 2 {
+		t.Errorf("fetches = %d, want 2", fetches)
+	}
+}
+
+func TestVerifyStaleKeysWhileEndpointDown(t *testing.T) {
+	server := newJWKSServer(t, "key-1")
+	v := newTestVerifier(t, server, func(o *Options) {
+		o.CacheTTL = 10 * time.Millisecond
+		o.MinRefreshInterval = 10 * time.Millisecond
+	})
+	token := server.sign(t, "key-1", nil)
+
+	if _, err := v.Verify(context.Background(), token); err != nil {
+		t.Fatal(err)
+	}
+
+	server.setFailing(true)
+	time.Sleep(20 * time.Millisecond)
+
+	// The stale key is still used while a refresh runs, and fails, in the background
+	for range 3 {
+		if _, err := v.Verify(context.Background(), token); err != nil {
+			t.Fatalf("known key rejected while the endpoint is down: %v", err)
+		}
+	}
+}